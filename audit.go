@@ -0,0 +1,361 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	v1beta1 "github.com/stevendborrelli/function-deletion-protection/input/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// An AuditStatus is the outcome of auditing a resource.
+type AuditStatus string
+
+const (
+	// AuditStatusProtected resources would be protected by a Usage.
+	AuditStatusProtected AuditStatus = "Protected"
+	// AuditStatusNotProtected resources have a protection label or annotation
+	// but would not be protected by a Usage.
+	AuditStatusNotProtected AuditStatus = "NotProtected"
+	// AuditStatusMissingProtection resources match a must-protect rule but
+	// would not be protected by a Usage.
+	AuditStatusMissingProtection AuditStatus = "MissingProtection"
+
+	// AuditReasonDesiredOnly is reported for resources that are desired but
+	// not observed. Usages are only created for resources that exist.
+	AuditReasonDesiredOnly = "resource is desired but not observed, so no Usage is created until it exists"
+	// AuditReasonExcluded is reported for resources matched by an Exclude
+	// rule.
+	AuditReasonExcluded = "resource is excluded by a rule"
+	// AuditReasonNoMatch is reported for resources that nothing protects.
+	AuditReasonNoMatch = "no label, annotation, rule or expression protects the resource"
+)
+
+// AuditCmd reports which resources would be protected by an Input.
+type AuditCmd struct {
+	Paths            []string `arg:""                                                                                             help:"YAML files or directories of YAML files containing the observed resources, for example exported cluster state." type:"path"`
+	FunctionInput    string   `help:"A YAML file containing the function's Input."                                                short:"i"                                                                                                            type:"existingfile"`
+	DesiredResources string   `help:"A YAML file or directory of YAML files containing desired resources that may not exist yet." type:"path"`
+	MustProtect      string   `help:"A YAML file containing a list of rules that select resources that must be protected."        type:"existingfile"`
+	Output           string   `default:"table"                                                                                    enum:"table,json,junit"                                                                                              help:"Output format. One of table, json or junit." short:"o"`
+	Strict           bool     `help:"Also fail if a resource with a protection label or annotation would not be protected."`
+}
+
+// Run the audit and write the report. It returns an error if a resource that
+// must be protected would not be.
+func (c *AuditCmd) Run() error {
+	in := &v1beta1.Input{}
+	if c.FunctionInput != "" {
+		objs, err := ReadObjects(c.FunctionInput)
+		if err != nil {
+			return errors.Wrap(err, "cannot read function input")
+		}
+		if len(objs) != 1 {
+			return errors.Errorf("function input %s must contain exactly one object, found %d", c.FunctionInput, len(objs))
+		}
+		if err := convertViaJSON(in, objs[0].Object); err != nil {
+			return errors.Wrap(err, "cannot convert function input")
+		}
+	}
+	policy, err := NewPolicy(in, nil)
+	if err != nil {
+		return errors.Wrap(err, "cannot build protection policy")
+	}
+
+	var mustProtect []rule
+	if c.MustProtect != "" {
+		mustProtect, err = ReadMustProtectRules(c.MustProtect)
+		if err != nil {
+			return err
+		}
+	}
+
+	var observed []*unstructured.Unstructured
+	for _, p := range c.Paths {
+		objs, err := ReadObjects(p)
+		if err != nil {
+			return errors.Wrap(err, "cannot read observed resources")
+		}
+		observed = append(observed, objs...)
+	}
+	var desired []*unstructured.Unstructured
+	if c.DesiredResources != "" {
+		desired, err = ReadObjects(c.DesiredResources)
+		if err != nil {
+			return errors.Wrap(err, "cannot read desired resources")
+		}
+	}
+
+	report := Audit(policy, mustProtect, observed, desired)
+	switch c.Output {
+	case "json":
+		err = WriteAuditJSON(os.Stdout, report)
+	case "junit":
+		err = WriteAuditJUnit(os.Stdout, report, c.Strict)
+	default:
+		err = WriteAuditTable(os.Stdout, report)
+	}
+	if err != nil {
+		return err
+	}
+
+	if n := report.Summary.MissingProtection; n > 0 {
+		return errors.Errorf("%d resources must be protected but would not be", n)
+	}
+	if n := report.Summary.NotProtected; c.Strict && n > 0 {
+		return errors.Errorf("%d resources have a protection label or annotation but would not be protected", n)
+	}
+	return nil
+}
+
+// ReadMustProtectRules reads a YAML list of rules that select resources that
+// must be protected.
+func ReadMustProtectRules(path string) ([]rule, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read must-protect rules %s", path)
+	}
+	rules := []v1beta1.Rule{}
+	if err := yaml.Unmarshal(b, &rules); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse must-protect rules %s", path)
+	}
+	compiled := make([]rule, 0, len(rules))
+	for i, r := range rules {
+		if r.Action == v1beta1.RuleActionExclude {
+			return nil, errors.Errorf("must-protect rule %d %q cannot exclude resources", i, r.Name)
+		}
+		cr, err := compileRule(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot compile must-protect rule %d %q", i, r.Name)
+		}
+		compiled = append(compiled, cr)
+	}
+	return compiled, nil
+}
+
+// An AuditReport reports protection coverage of a set of resources.
+type AuditReport struct {
+	Summary AuditSummary `json:"summary"`
+
+	// Resources are the resources that are protected, have a protection label
+	// or annotation, or must be protected. Other resources are only counted.
+	Resources []AuditResult `json:"resources"`
+}
+
+// AuditSummary counts the audited resources by status.
+type AuditSummary struct {
+	Scanned           int `json:"scanned"`
+	Protected         int `json:"protected"`
+	NotProtected      int `json:"notProtected"`
+	MissingProtection int `json:"missingProtection"`
+}
+
+// An AuditResult is the outcome of auditing a resource.
+type AuditResult struct {
+	Resource ObjectReference `json:"resource"`
+	// ResourceName is the composition resource name of a composed resource.
+	ResourceName string      `json:"resourceName,omitempty"`
+	Status       AuditStatus `json:"status"`
+	Reason       string      `json:"reason"`
+	// MustProtect is the name of the must-protect rule that matched.
+	MustProtect string `json:"mustProtect,omitempty"`
+}
+
+// Audit evaluates the policy against the observed resources, and the desired
+// resources that are not observed. Usages are never audited.
+func Audit(policy *Policy, mustProtect []rule, observed, desired []*unstructured.Unstructured) AuditReport {
+	seen := map[ObjectReference]bool{}
+	for _, o := range observed {
+		seen[objectReference(o)] = true
+	}
+
+	report := AuditReport{Resources: []AuditResult{}}
+	audit := func(u *unstructured.Unstructured, exists bool) {
+		if isUsage(u) {
+			return
+		}
+		report.Summary.Scanned++
+		name := resource.Name(u.GetAnnotations()[AnnotationCompositionResourceName])
+		r := AuditResult{Resource: objectReference(u), ResourceName: string(name)}
+
+		reason, protect := policy.Evaluate(name, u)
+		labeled, markerReason := policy.markerReason(u)
+		// The Usage of a resource with the allow-deletion annotation is
+		// dropped.
+		ticket, until, allowed := AllowDeletion(u, policy.time())
+		switch {
+		case protect && allowed:
+			r.Status, r.Reason = AuditStatusNotProtected, allowDeletionReason(ticket, until)
+		case protect && exists:
+			r.Status, r.Reason = AuditStatusProtected, reason
+		case protect:
+			r.Status, r.Reason = AuditStatusNotProtected, AuditReasonDesiredOnly
+		case labeled && policy.Excluded(name, u):
+			r.Status, r.Reason = AuditStatusNotProtected, AuditReasonExcluded
+		case labeled:
+			r.Status, r.Reason = AuditStatusNotProtected, markerReason
+		default:
+			r.Reason = AuditReasonNoMatch
+		}
+
+		if r.Status != AuditStatusProtected {
+			for _, mp := range mustProtect {
+				if mp.matches(name, u) {
+					r.Status, r.MustProtect = AuditStatusMissingProtection, mp.name
+					if policy.Excluded(name, u) {
+						r.Reason = AuditReasonExcluded
+					}
+					break
+				}
+			}
+		}
+
+		switch r.Status {
+		case AuditStatusProtected:
+			report.Summary.Protected++
+		case AuditStatusNotProtected:
+			report.Summary.NotProtected++
+		case AuditStatusMissingProtection:
+			report.Summary.MissingProtection++
+		default:
+			return
+		}
+		report.Resources = append(report.Resources, r)
+	}
+
+	for _, o := range observed {
+		audit(o, true)
+	}
+	for _, d := range desired {
+		if !seen[objectReference(d)] {
+			audit(d, false)
+		}
+	}
+
+	slices.SortStableFunc(report.Resources, func(a, b AuditResult) int {
+		return strings.Compare(auditName(a), auditName(b))
+	})
+	return report
+}
+
+// markerReason returns true if the resource has one of the policy's
+// protection labels or annotations, and explains why it doesn't protect the
+// resource.
+func (p *Policy) markerReason(u *unstructured.Unstructured) (bool, string) {
+	markers := p.markers
+	if len(markers.LabelKeys) == 0 && len(markers.AnnotationKeys) == 0 {
+		markers = DefaultProtectionMarkers()
+	}
+	if _, at, ok := ProtectResource(markers, p.time(), u); !ok && !at.IsZero() {
+		return true, "protection expired at " + at.UTC().Format(time.RFC3339)
+	}
+	labels := u.GetLabels()
+	for _, k := range markers.LabelKeys {
+		if v, ok := labels[k]; ok {
+			return true, fmt.Sprintf("label %s has value %q, which is not an accepted value", k, v)
+		}
+	}
+	annotations := u.GetAnnotations()
+	for _, k := range markers.AnnotationKeys {
+		if v, ok := annotations[k]; ok {
+			return true, fmt.Sprintf("annotation %s has value %q, which is not an accepted value", k, v)
+		}
+	}
+	return false, ""
+}
+
+// auditName returns a human readable name for an audited resource.
+func auditName(r AuditResult) string {
+	name := r.Resource.Kind + "/" + r.Resource.Name
+	if r.Resource.Namespace != "" {
+		name = r.Resource.Kind + "/" + r.Resource.Namespace + "/" + r.Resource.Name
+	}
+	return name
+}
+
+// WriteAuditTable writes the report as a table.
+func WriteAuditTable(w io.Writer, r AuditReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tAPIVERSION\tKIND\tNAMESPACE\tNAME\tRESOURCE NAME\tREASON")
+	for _, res := range r.Resources {
+		reason := strings.TrimPrefix(res.Reason, ProtectionReason)
+		if res.MustProtect != "" {
+			reason = fmt.Sprintf("%s (must-protect rule %q)", reason, res.MustProtect)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", res.Status, res.Resource.APIVersion, res.Resource.Kind, res.Resource.Namespace, res.Resource.Name, res.ResourceName, reason)
+	}
+	if err := tw.Flush(); err != nil {
+		return errors.Wrap(err, "cannot write table")
+	}
+	_, err := fmt.Fprintf(w, "\nScanned %d resources: %d protected, %d not protected, %d missing protection\n",
+		r.Summary.Scanned, r.Summary.Protected, r.Summary.NotProtected, r.Summary.MissingProtection)
+	return errors.Wrap(err, "cannot write summary")
+}
+
+// WriteAuditJSON writes the report as JSON.
+func WriteAuditJSON(w io.Writer, r AuditReport) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return errors.Wrap(e.Encode(r), "cannot write JSON")
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+}
+
+// WriteAuditJUnit writes the report as JUnit XML, with a test case for each
+// reported resource. Resources missing protection are failures. Resources
+// that are not protected are only failures if strict is true.
+func WriteAuditJUnit(w io.Writer, r AuditReport, strict bool) error {
+	suite := junitTestSuite{Name: "function-deletion-protection", Tests: len(r.Resources)}
+	for _, res := range r.Resources {
+		tc := junitTestCase{ClassName: res.Resource.APIVersion, Name: auditName(res)}
+		if res.Status == AuditStatusMissingProtection || (strict && res.Status == AuditStatusNotProtected) {
+			tc.Failure = &junitFailure{Type: string(res.Status), Message: res.Reason}
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return errors.Wrap(err, "cannot write JUnit XML")
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(junitTestSuites{TestSuites: []junitTestSuite{suite}}); err != nil {
+		return errors.Wrap(err, "cannot write JUnit XML")
+	}
+	_, err := io.WriteString(w, "\n")
+	return errors.Wrap(err, "cannot write JUnit XML")
+}
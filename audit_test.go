@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1beta1 "github.com/stevendborrelli/function-deletion-protection/input/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestAudit(t *testing.T) {
+	obj := func(kind, name string, labels map[string]string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "ec2.aws.upbound.io/v1beta1",
+			"kind":       kind,
+			"metadata": map[string]any{
+				"name":        name,
+				"annotations": map[string]any{AnnotationCompositionResourceName: name},
+			},
+		}}
+		if labels != nil {
+			u.SetLabels(labels)
+		}
+		return u
+	}
+	ref := func(kind, name string) ObjectReference {
+		return ObjectReference{APIVersion: "ec2.aws.upbound.io/v1beta1", Kind: kind, Name: name}
+	}
+	protected := map[string]string{ProtectionLabelBlockDeletion: "true"}
+	allowed := func(u *unstructured.Unstructured) *unstructured.Unstructured {
+		annotations := u.GetAnnotations()
+		annotations[ProtectionAnnotationAllowDeletion] = "OPS-1234"
+		u.SetAnnotations(annotations)
+		return u
+	}
+
+	type args struct {
+		in          *v1beta1.Input
+		mustProtect []v1beta1.Rule
+		observed    []*unstructured.Unstructured
+		desired     []*unstructured.Unstructured
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   AuditReport
+	}{
+		"Protected": {
+			reason: "Observed resources with the protection label should be protected, and other resources only counted",
+			args: args{
+				in:       &v1beta1.Input{},
+				observed: []*unstructured.Unstructured{obj("VPC", "vpc", protected), obj("Subnet", "subnet", nil)},
+			},
+			want: AuditReport{
+				Summary: AuditSummary{Scanned: 2, Protected: 1},
+				Resources: []AuditResult{
+					{Resource: ref("VPC", "vpc"), ResourceName: "vpc", Status: AuditStatusProtected, Reason: ProtectionReasonLabel},
+				},
+			},
+		},
+		"DesiredOnly": {
+			reason: "Labeled resources that are desired but not observed would not be protected",
+			args: args{
+				in:       &v1beta1.Input{},
+				observed: []*unstructured.Unstructured{obj("VPC", "vpc", protected)},
+				desired:  []*unstructured.Unstructured{obj("VPC", "vpc", protected), obj("Subnet", "subnet", protected)},
+			},
+			want: AuditReport{
+				Summary: AuditSummary{Scanned: 2, Protected: 1, NotProtected: 1},
+				Resources: []AuditResult{
+					{Resource: ref("Subnet", "subnet"), ResourceName: "subnet", Status: AuditStatusNotProtected, Reason: AuditReasonDesiredOnly},
+					{Resource: ref("VPC", "vpc"), ResourceName: "vpc", Status: AuditStatusProtected, Reason: ProtectionReasonLabel},
+				},
+			},
+		},
+		"LabeledButNotProtected": {
+			reason: "Resources with an unaccepted label value or an Exclude rule would not be protected",
+			args: args{
+				in: &v1beta1.Input{Rules: []v1beta1.Rule{{Action: v1beta1.RuleActionExclude, Kind: "Subnet"}}},
+				observed: []*unstructured.Unstructured{
+					obj("VPC", "vpc", map[string]string{ProtectionLabelBlockDeletion: "yes"}),
+					obj("Subnet", "subnet", protected),
+				},
+			},
+			want: AuditReport{
+				Summary: AuditSummary{Scanned: 2, NotProtected: 2},
+				Resources: []AuditResult{
+					{Resource: ref("Subnet", "subnet"), ResourceName: "subnet", Status: AuditStatusNotProtected, Reason: AuditReasonExcluded},
+					{Resource: ref("VPC", "vpc"), ResourceName: "vpc", Status: AuditStatusNotProtected, Reason: `label protection.fn.crossplane.io/block-deletion has value "yes", which is not an accepted value`},
+				},
+			},
+		},
+		"AllowDeletion": {
+			reason: "Resources with the allow-deletion annotation would not be protected, and the ticket should be reported",
+			args: args{
+				in:       &v1beta1.Input{},
+				observed: []*unstructured.Unstructured{allowed(obj("VPC", "vpc", protected))},
+			},
+			want: AuditReport{
+				Summary: AuditSummary{Scanned: 1, NotProtected: 1},
+				Resources: []AuditResult{
+					{Resource: ref("VPC", "vpc"), ResourceName: "vpc", Status: AuditStatusNotProtected, Reason: `deletion is allowed by ticket "OPS-1234"`},
+				},
+			},
+		},
+		"MissingProtection": {
+			reason: "Resources matching a must-protect rule that would not be protected should be reported",
+			args: args{
+				in:          &v1beta1.Input{},
+				mustProtect: []v1beta1.Rule{{Name: "networks", Kind: "VPC"}},
+				observed:    []*unstructured.Unstructured{obj("VPC", "vpc", nil)},
+			},
+			want: AuditReport{
+				Summary: AuditSummary{Scanned: 1, MissingProtection: 1},
+				Resources: []AuditResult{
+					{Resource: ref("VPC", "vpc"), ResourceName: "vpc", Status: AuditStatusMissingProtection, Reason: AuditReasonNoMatch, MustProtect: "networks"},
+				},
+			},
+		},
+		"Usages": {
+			reason: "Usages should not be audited",
+			args: args{
+				in: &v1beta1.Input{},
+				observed: []*unstructured.Unstructured{{Object: map[string]any{
+					"apiVersion": ProtectionGroupVersion,
+					"kind":       "ClusterUsage",
+					"metadata":   map[string]any{"name": "vpc-usage"},
+				}}},
+			},
+			want: AuditReport{Resources: []AuditResult{}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := NewPolicy(tc.args.in, nil)
+			if err != nil {
+				t.Fatalf("NewPolicy(...): %v", err)
+			}
+			mp := make([]rule, len(tc.args.mustProtect))
+			for i, r := range tc.args.mustProtect {
+				if mp[i], err = compileRule(r); err != nil {
+					t.Fatalf("compileRule(...): %v", err)
+				}
+			}
+			got := Audit(p, mp, tc.args.observed, tc.args.desired)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nAudit(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWriteAuditJUnit(t *testing.T) {
+	r := AuditReport{
+		Resources: []AuditResult{
+			{Resource: ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "prod"}, Status: AuditStatusProtected, Reason: ProtectionReasonLabel},
+			{Resource: ObjectReference{APIVersion: "v1", Kind: "ConfigMap", Name: "config", Namespace: "prod"}, Status: AuditStatusNotProtected, Reason: AuditReasonExcluded},
+			{Resource: ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "dev"}, Status: AuditStatusMissingProtection, Reason: AuditReasonNoMatch},
+		},
+	}
+
+	cases := map[string]struct {
+		reason string
+		strict bool
+		want   string
+	}{
+		"MissingProtectionFails": {
+			reason: "Only resources missing protection should fail",
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="function-deletion-protection" tests="3" failures="1">
+    <testcase classname="v1" name="Namespace/prod"></testcase>
+    <testcase classname="v1" name="ConfigMap/prod/config"></testcase>
+    <testcase classname="v1" name="Namespace/dev">
+      <failure type="MissingProtection" message="no label, annotation, rule or expression protects the resource"></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+		},
+		"Strict": {
+			reason: "Labeled resources that would not be protected should also fail in strict mode",
+			strict: true,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="function-deletion-protection" tests="3" failures="2">
+    <testcase classname="v1" name="Namespace/prod"></testcase>
+    <testcase classname="v1" name="ConfigMap/prod/config">
+      <failure type="NotProtected" message="resource is excluded by a rule"></failure>
+    </testcase>
+    <testcase classname="v1" name="Namespace/dev">
+      <failure type="MissingProtection" message="no label, annotation, rule or expression protects the resource"></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			if err := WriteAuditJUnit(b, r, tc.strict); err != nil {
+				t.Fatalf("%s\nWriteAuditJUnit(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, b.String()); diff != "" {
+				t.Errorf("%s\nWriteAuditJUnit(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// A DeletionOverride records a Usage that wasn't generated because its
+// resource has the allow-deletion annotation.
+type DeletionOverride struct {
+	// Resource is the resource whose deletion is allowed.
+	Resource ObjectReference
+	// Ticket is the value of the allow-deletion annotation.
+	Ticket string
+	// Usage is the name of the Usage that wasn't generated.
+	Usage string
+	// Until is when the override expires. It is zero if it doesn't expire.
+	Until time.Time
+}
+
+// AllowDeletion returns the ticket of the allow-deletion annotation of a
+// resource, and when it expires, if deletion of the resource is allowed at the
+// supplied time. The annotation must have a value. An expiry that isn't valid
+// doesn't allow deletion.
+func AllowDeletion(u *unstructured.Unstructured, now time.Time) (string, time.Time, bool) {
+	if u == nil || u.Object == nil {
+		return "", time.Time{}, false
+	}
+	annotations := u.GetAnnotations()
+	ticket := annotations[ProtectionAnnotationAllowDeletion]
+	if ticket == "" {
+		return "", time.Time{}, false
+	}
+	v, ok := annotations[ProtectionAnnotationAllowDeletionUntil]
+	if !ok {
+		return ticket, time.Time{}, true
+	}
+	until, ok := parseExpiry(v, u.GetCreationTimestamp().Time)
+	if !ok || !now.Before(until) {
+		return "", time.Time{}, false
+	}
+	return ticket, until, true
+}
+
+// warning returns a warning recording the override.
+func (o DeletionOverride) warning() error {
+	r := o.Resource.Kind + " " + o.Resource.Name
+	if o.Resource.Namespace != "" {
+		r = o.Resource.Kind + " " + o.Resource.Namespace + "/" + o.Resource.Name
+	}
+	if o.Until.IsZero() {
+		return errors.Errorf("deletion of %s is allowed by ticket %q: dropped Usage %s", r, o.Ticket, o.Usage)
+	}
+	return errors.Errorf("deletion of %s is allowed by ticket %q until %s: dropped Usage %s", r, o.Ticket, o.Until.UTC().Format(time.RFC3339), o.Usage)
+}
+
+// allowDeletionReason explains that deletion of a resource is allowed by the
+// ticket of its allow-deletion annotation.
+func allowDeletionReason(ticket string, until time.Time) string {
+	if until.IsZero() {
+		return fmt.Sprintf("deletion is allowed by ticket %q", ticket)
+	}
+	return fmt.Sprintf("deletion is allowed by ticket %q until %s", ticket, until.UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+func TestAllowDeletion(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	withAnnotations := func(annotations map[string]any) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"metadata": map[string]any{"annotations": annotations},
+		}}
+	}
+
+	type want struct {
+		ticket string
+		until  time.Time
+		ok     bool
+	}
+	cases := map[string]struct {
+		reason string
+		u      *unstructured.Unstructured
+		want   want
+	}{
+		"NoAnnotation": {
+			reason: "Deletion shouldn't be allowed without the annotation",
+			u:      withAnnotations(nil),
+			want:   want{},
+		},
+		"EmptyTicket": {
+			reason: "Deletion shouldn't be allowed without a ticket",
+			u:      withAnnotations(map[string]any{ProtectionAnnotationAllowDeletion: ""}),
+			want:   want{},
+		},
+		"Ticket": {
+			reason: "Deletion should be allowed by a ticket without an expiry",
+			u:      withAnnotations(map[string]any{ProtectionAnnotationAllowDeletion: "OPS-1234"}),
+			want:   want{ticket: "OPS-1234", ok: true},
+		},
+		"NotExpired": {
+			reason: "Deletion should be allowed until the override expires",
+			u: withAnnotations(map[string]any{
+				ProtectionAnnotationAllowDeletion:      "OPS-1234",
+				ProtectionAnnotationAllowDeletionUntil: "2026-10-16T13:00:00Z",
+			}),
+			want: want{ticket: "OPS-1234", until: time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC), ok: true},
+		},
+		"Expired": {
+			reason: "Deletion shouldn't be allowed once the override expires",
+			u: withAnnotations(map[string]any{
+				ProtectionAnnotationAllowDeletion:      "OPS-1234",
+				ProtectionAnnotationAllowDeletionUntil: "2026-10-16T11:00:00Z",
+			}),
+			want: want{},
+		},
+		"InvalidExpiry": {
+			reason: "Deletion shouldn't be allowed if the expiry isn't valid",
+			u: withAnnotations(map[string]any{
+				ProtectionAnnotationAllowDeletion:      "OPS-1234",
+				ProtectionAnnotationAllowDeletionUntil: "tomorrow",
+			}),
+			want: want{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ticket, until, ok := AllowDeletion(tc.u, now)
+			if diff := cmp.Diff(tc.want, want{ticket: ticket, until: until, ok: ok}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nAllowDeletion(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRunFunctionAllowDeletion(t *testing.T) {
+	req := func(annotations string) *fnv1.RunFunctionRequest {
+		xr := resource.MustStructJSON(`{
+			"apiVersion": "test.crossplane.io/v1",
+			"kind": "TestXR",
+			"metadata": {
+				"name": "my-test-xr"
+			}
+		}`)
+		bucket := resource.MustStructJSON(`{
+			"apiVersion": "s3.aws.upbound.io/v1beta1",
+			"kind": "Bucket",
+			"metadata": {
+				"name": "my-bucket",
+				"labels": {
+					"protection.fn.crossplane.io/block-deletion": "true"
+				},
+				"annotations": ` + annotations + `
+			}
+		}`)
+		return &fnv1.RunFunctionRequest{
+			Input: resource.MustStructJSON(`{
+				"apiVersion": "protection.fn.crossplane.io/v1beta1",
+				"kind": "Input",
+				"cacheTTL": "1h"
+			}`),
+			Observed: &fnv1.State{
+				Composite: &fnv1.Resource{Resource: xr},
+				Resources: map[string]*fnv1.Resource{"bucket": {Resource: bucket}},
+			},
+			Desired: &fnv1.State{
+				Composite: &fnv1.Resource{Resource: xr},
+				Resources: map[string]*fnv1.Resource{"bucket": {Resource: bucket}},
+			},
+		}
+	}
+
+	type want struct {
+		usages  int
+		ttl     time.Duration
+		results []string
+	}
+	cases := map[string]struct {
+		reason      string
+		annotations string
+		want        want
+	}{
+		"Protected": {
+			reason:      "A protected resource and its composite should be protected without an override",
+			annotations: `{}`,
+			want: want{
+				usages:  2,
+				ttl:     time.Hour,
+				results: []string{"Deletion of the composite and 1 composed resource is blocked by Usages"},
+			},
+		},
+		"Override": {
+			reason:      "An override should drop the Usage of the resource and its composite, with a warning, until it expires",
+			annotations: `{"protection.fn.crossplane.io/allow-deletion": "OPS-1234", "protection.fn.crossplane.io/allow-deletion-until": "2026-10-16T12:30:00Z"}`,
+			want: want{
+				usages:  0,
+				ttl:     30 * time.Minute,
+				results: []string{`deletion of Bucket my-bucket is allowed by ticket "OPS-1234" until 2026-10-16T12:30:00Z: dropped Usage bucket-my-bucket-264df3-fn-protection`},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+			f := &Function{log: logging.NewNopLogger(), now: func() time.Time { return now }}
+			rsp, err := f.RunFunction(context.Background(), req(tc.annotations))
+			if err != nil {
+				t.Fatalf("%s\nf.RunFunction(...): %v", tc.reason, err)
+			}
+			// The bucket itself is always desired.
+			if diff := cmp.Diff(tc.want.usages, len(rsp.GetDesired().GetResources())-1); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want usages, +got usages:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.ttl, rsp.GetMeta().GetTtl().AsDuration()); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want ttl, +got ttl:\n%s", tc.reason, diff)
+			}
+			results := []string{}
+			for _, r := range rsp.GetResults() {
+				results = append(results, r.GetMessage())
+			}
+			if diff := cmp.Diff(tc.want.results, results); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want results, +got results:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
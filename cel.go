@@ -0,0 +1,75 @@
+package main
+
+import (
+	"github.com/google/cel-go/cel"
+	v1beta1 "github.com/stevendborrelli/function-deletion-protection/input/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+const (
+	// celVariableObject is the CEL variable holding the evaluated resource.
+	celVariableObject = "object"
+	// celVariableComposite is the CEL variable holding the observed composite.
+	celVariableComposite = "composite"
+)
+
+// expression is a compiled v1beta1.Expression.
+type expression struct {
+	name    string
+	program cel.Program
+}
+
+// compileExpressions compiles CEL expressions. The returned error names the
+// first expression that could not be compiled.
+func compileExpressions(exprs []v1beta1.Expression) ([]expression, error) {
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	env, err := cel.NewEnv(
+		cel.Variable(celVariableObject, cel.DynType),
+		cel.Variable(celVariableComposite, cel.DynType),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create CEL environment")
+	}
+
+	compiled := make([]expression, 0, len(exprs))
+	for _, e := range exprs {
+		ast, iss := env.Compile(e.Expression)
+		if iss.Err() != nil {
+			return nil, errors.Wrapf(iss.Err(), "cannot compile expression %q", e.Expression)
+		}
+		if !ast.OutputType().IsExactType(cel.BoolType) && !ast.OutputType().IsExactType(cel.DynType) {
+			return nil, errors.Errorf("expression %q must evaluate to a bool, not %s", e.Expression, ast.OutputType())
+		}
+		prg, err := env.Program(ast)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot create program for expression %q", e.Expression)
+		}
+		name := e.Name
+		if name == "" {
+			name = e.Expression
+		}
+		compiled = append(compiled, expression{name: name, program: prg})
+	}
+	return compiled, nil
+}
+
+// matches returns true if the expression evaluates to true for the resource.
+// Evaluation errors, such as a missing field, are treated as false.
+func (e expression) matches(u *unstructured.Unstructured, composite map[string]any) bool {
+	if u == nil || u.Object == nil || u.GetKind() == "" {
+		return false
+	}
+	out, _, err := e.program.Eval(map[string]any{
+		celVariableObject:    u.Object,
+		celVariableComposite: composite,
+	})
+	if err != nil {
+		return false
+	}
+	ok, isBool := out.Value().(bool)
+	return isBool && ok
+}
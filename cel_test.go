@@ -0,0 +1,149 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1beta1 "github.com/stevendborrelli/function-deletion-protection/input/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestExpressionMatches(t *testing.T) {
+	instance := &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "rds.aws.upbound.io/v1beta1",
+			"kind":       "Instance",
+			"metadata": map[string]any{
+				"name": "my-db",
+				"labels": map[string]any{
+					"env": "prod",
+				},
+			},
+			"spec": map[string]any{
+				"forProvider": map[string]any{
+					"region": "us-east-1",
+				},
+			},
+		},
+	}
+	composite := map[string]any{
+		"apiVersion": "example.org/v1",
+		"kind":       "XDatabase",
+		"spec": map[string]any{
+			"tier": "critical",
+		},
+	}
+
+	type args struct {
+		expression string
+		u          *unstructured.Unstructured
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   bool
+	}{
+		"MatchingObject": {
+			reason: "An expression that is true for the object should match",
+			args: args{
+				expression: `object.spec.forProvider.region == "us-east-1" && object.metadata.labels.env == "prod"`,
+				u:          instance,
+			},
+			want: true,
+		},
+		"NonMatchingObject": {
+			reason: "An expression that is false for the object should not match",
+			args: args{
+				expression: `object.spec.forProvider.region == "eu-west-1"`,
+				u:          instance,
+			},
+			want: false,
+		},
+		"MatchingComposite": {
+			reason: "An expression should be able to reference the composite",
+			args: args{
+				expression: `composite.spec.tier == "critical"`,
+				u:          instance,
+			},
+			want: true,
+		},
+		"MissingField": {
+			reason: "An expression referencing a missing field should not match",
+			args: args{
+				expression: `object.spec.forProvider.engine == "postgres"`,
+				u:          instance,
+			},
+			want: false,
+		},
+		"NonBoolResult": {
+			reason: "An expression that does not evaluate to a bool should not match",
+			args: args{
+				expression: `object.metadata.labels.env`,
+				u:          instance,
+			},
+			want: false,
+		},
+		"EmptyObject": {
+			reason: "An expression should never match an object without a kind",
+			args: args{
+				expression: `true`,
+				u:          &unstructured.Unstructured{Object: map[string]any{}},
+			},
+			want: false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			exprs, err := compileExpressions([]v1beta1.Expression{{Expression: tc.args.expression}})
+			if err != nil {
+				t.Fatalf("compileExpressions(...): %v", err)
+			}
+			got := exprs[0].matches(tc.args.u, composite)
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\ne.matches(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCompileExpressions(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		exprs   []v1beta1.Expression
+		wantErr bool
+	}{
+		"Valid": {
+			reason: "Valid boolean expressions should compile",
+			exprs: []v1beta1.Expression{
+				{Name: "prod", Expression: `object.metadata.labels.env == "prod"`},
+				{Expression: `has(object.spec.forProvider)`},
+			},
+		},
+		"SyntaxError": {
+			reason:  "An expression with a syntax error should return an error",
+			exprs:   []v1beta1.Expression{{Expression: `object.metadata.labels.env ==`}},
+			wantErr: true,
+		},
+		"NotBool": {
+			reason:  "An expression with a non-bool type should return an error",
+			exprs:   []v1beta1.Expression{{Expression: `"prod"`}},
+			wantErr: true,
+		},
+		"UndeclaredVariable": {
+			reason:  "An expression referencing an unknown variable should return an error",
+			exprs:   []v1beta1.Expression{{Expression: `resource.kind == "Instance"`}},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := compileExpressions(tc.exprs)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("%s\ncompileExpressions(...): want error %t, got %v", tc.reason, tc.wantErr, err)
+			}
+		})
+	}
+}
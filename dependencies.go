@@ -0,0 +1,280 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+// referenceFieldPaths are the fields of a managed resource that contain
+// references to other managed resources.
+var referenceFieldPaths = [][]string{
+	{"spec", "forProvider"},
+	{"spec", "initProvider"},
+}
+
+// referenceKindSuffixes are stripped from a reference field's prefix to guess
+// the kind it refers to, for example vpcIdSelector refers to a VPC.
+var referenceKindSuffixes = []string{"Ids", "Id", "Arns", "Arn", "Names", "Name"}
+
+// A Dependency records that the composed resource By references the composed
+// resource Of, so Of must not be deleted before By.
+type Dependency struct {
+	Of resource.Name
+	By resource.Name
+}
+
+// reference is a *Ref, *Refs or *Selector field of a managed resource.
+type reference struct {
+	// kind is the lowercase kind guessed from the field name. It may be empty.
+	kind string
+
+	// name and namespace are set for *Ref and *Refs fields.
+	name      string
+	namespace string
+
+	// matchLabels and matchControllerRef are set for *Selector fields.
+	selector           bool
+	matchLabels        map[string]string
+	matchControllerRef bool
+}
+
+// InferDependencies returns the dependencies between the supplied composed
+// resources, sorted by By and then Of. References are read from the *Ref,
+// *Refs and *Selector fields of spec.forProvider and spec.initProvider.
+// Selectors are only resolved when the referencing field is unresolved, and
+// match composed resources whose kind ends with the field's prefix, for
+// example vpcIdSelector matches a VPC. The prefix is also used to choose
+// between resources of different kinds with the referenced name.
+func InferDependencies(resources map[resource.Name]*unstructured.Unstructured) []Dependency {
+	deps := []Dependency{}
+	for by, u := range resources {
+		of := map[resource.Name]bool{}
+		for _, ref := range references(u) {
+			matched := []resource.Name{}
+			for name, candidate := range resources {
+				if name != by && ref.resolves(u, candidate) {
+					matched = append(matched, name)
+				}
+			}
+			// Different kinds of resource may share a name. Use the kind
+			// guessed from the field to choose between them.
+			if !ref.selector && len(matched) > 1 {
+				matched = slices.DeleteFunc(matched, func(name resource.Name) bool {
+					return !ref.matchesKind(resources[name])
+				})
+			}
+			for _, name := range matched {
+				of[name] = true
+			}
+		}
+		for name := range of {
+			deps = append(deps, Dependency{Of: name, By: by})
+		}
+	}
+	sort.Slice(deps, func(i, j int) bool {
+		if deps[i].By != deps[j].By {
+			return deps[i].By < deps[j].By
+		}
+		return deps[i].Of < deps[j].Of
+	})
+	return deps
+}
+
+// references returns the references to other resources in a managed
+// resource's spec.forProvider and spec.initProvider.
+func references(u *unstructured.Unstructured) []reference {
+	refs := []reference{}
+	if u == nil || u.Object == nil {
+		return refs
+	}
+	for _, fp := range referenceFieldPaths {
+		params, found, err := unstructured.NestedMap(u.Object, fp...)
+		if err != nil || !found {
+			continue
+		}
+		refs = appendReferences(refs, params)
+	}
+	return refs
+}
+
+// appendReferences appends references found in v, which may be a map, a list
+// or a scalar, to refs.
+func appendReferences(refs []reference, v any) []reference {
+	switch val := v.(type) {
+	case []any:
+		for _, item := range val {
+			refs = appendReferences(refs, item)
+		}
+	case map[string]any:
+		for field, fv := range val {
+			switch {
+			case strings.HasSuffix(field, "Refs"):
+				kind := referenceKind(strings.TrimSuffix(field, "Refs"))
+				items, _ := fv.([]any)
+				for _, item := range items {
+					if ref, ok := nameReference(kind, item); ok {
+						refs = append(refs, ref)
+					}
+				}
+			case strings.HasSuffix(field, "Ref"):
+				kind := referenceKind(strings.TrimSuffix(field, "Ref"))
+				if ref, ok := nameReference(kind, fv); ok {
+					refs = append(refs, ref)
+				}
+			case strings.HasSuffix(field, "Selector"):
+				prefix := strings.TrimSuffix(field, "Selector")
+				// A resolved reference is more precise than the selector.
+				if _, ok := val[prefix+"Ref"]; ok {
+					continue
+				}
+				if _, ok := val[prefix+"Refs"]; ok {
+					continue
+				}
+				if ref, ok := selectorReference(referenceKind(prefix), fv); ok {
+					refs = append(refs, ref)
+				}
+			default:
+				refs = appendReferences(refs, fv)
+			}
+		}
+	}
+	return refs
+}
+
+// referenceKind guesses the lowercase kind a reference field refers to from
+// the field's prefix, for example vpcId refers to a VPC.
+func referenceKind(prefix string) string {
+	for _, s := range referenceKindSuffixes {
+		if trimmed := strings.TrimSuffix(prefix, s); trimmed != prefix && trimmed != "" {
+			return strings.ToLower(trimmed)
+		}
+	}
+	return strings.ToLower(prefix)
+}
+
+func nameReference(kind string, v any) (reference, bool) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return reference{}, false
+	}
+	name, _ := m["name"].(string)
+	if name == "" {
+		return reference{}, false
+	}
+	namespace, _ := m["namespace"].(string)
+	return reference{kind: kind, name: name, namespace: namespace}, true
+}
+
+func selectorReference(kind string, v any) (reference, bool) {
+	m, ok := v.(map[string]any)
+	if !ok || kind == "" {
+		return reference{}, false
+	}
+	ref := reference{kind: kind, selector: true, matchLabels: map[string]string{}}
+	ref.matchControllerRef, _ = m["matchControllerRef"].(bool)
+	if labels, ok := m["matchLabels"].(map[string]any); ok {
+		for k, lv := range labels {
+			s, _ := lv.(string)
+			ref.matchLabels[k] = s
+		}
+	}
+	// A selector without criteria could match resources outside the
+	// composition, so we can't infer a dependency from it.
+	if !ref.matchControllerRef && len(ref.matchLabels) == 0 {
+		return reference{}, false
+	}
+	return ref, true
+}
+
+// resolves returns true if the reference from resource u refers to the
+// candidate resource.
+func (r reference) resolves(u, candidate *unstructured.Unstructured) bool {
+	if candidate == nil || candidate.Object == nil || candidate.GetName() == "" {
+		return false
+	}
+	if !r.selector {
+		if candidate.GetName() != r.name {
+			return false
+		}
+		namespace := r.namespace
+		if namespace == "" {
+			namespace = u.GetNamespace()
+		}
+		// Cluster scoped resources can be referenced from any namespace.
+		if candidate.GetNamespace() != "" && candidate.GetNamespace() != namespace {
+			return false
+		}
+		return true
+	}
+	if !r.matchesKind(candidate) {
+		return false
+	}
+	if candidate.GetNamespace() != "" && candidate.GetNamespace() != u.GetNamespace() {
+		return false
+	}
+	labels := candidate.GetLabels()
+	for k, v := range r.matchLabels {
+		if lv, ok := labels[k]; !ok || lv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesKind returns true if the candidate's kind ends with the kind guessed
+// from the reference field.
+func (r reference) matchesKind(candidate *unstructured.Unstructured) bool {
+	return strings.HasSuffix(strings.ToLower(candidate.GetKind()), r.kind)
+}
+
+// dependencyResources returns the observed composed resources that can be
+// ordered, keyed by composition resource name. Usages and resources matched by
+// an Exclude rule are ignored.
+func dependencyResources(desiredComposed map[resource.Name]*resource.DesiredComposed, observedComposed map[resource.Name]resource.ObservedComposed, policy *Policy) map[resource.Name]*unstructured.Unstructured {
+	resources := map[resource.Name]*unstructured.Unstructured{}
+	for name, desired := range desiredComposed {
+		observed, ok := observedComposed[name]
+		if !ok || isUsage(&desired.Resource.Unstructured) || policy.Excluded(name, &desired.Resource.Unstructured, &observed.Resource.Unstructured) {
+			continue
+		}
+		resources[name] = &observed.Resource.Unstructured
+	}
+	return resources
+}
+
+// ProtectDependencies creates a Usage for each dependency between the
+// supplied composed resources, blocking deletion of the resource it's of until
+// the resource it's by has been deleted.
+func (f *Function) ProtectDependencies(deps []Dependency, resources map[resource.Name]*unstructured.Unstructured, reason string, policy *Policy, g *UsageGenerator) (map[resource.Name]*resource.DesiredComposed, error) {
+	dc := map[resource.Name]*resource.DesiredComposed{}
+	for _, d := range deps {
+		of, by := resources[d.Of], resources[d.By]
+		f.log.Debug("protecting dependency", "of", d.Of, "by", d.By)
+		usage, ok, err := g.Generate(of, by, reason, policy.ReplayDeletion(d.Of, of))
+		if err != nil {
+			return dc, err
+		}
+		if !ok {
+			continue
+		}
+		usageComposed := composed.New()
+		if err := convertViaJSON(usageComposed, usage); err != nil {
+			return dc, errors.Wrap(err, "cannot convert usage to unstructured")
+		}
+		key, err := g.Key(dependencyKey(d.By, d.Of), of, by)
+		if err != nil {
+			return dc, err
+		}
+		if err := addUsage(dc, key, usageComposed); err != nil {
+			return dc, err
+		}
+	}
+	return dc, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+func TestInferDependencies(t *testing.T) {
+	vpc := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "ec2.aws.upbound.io/v1beta1",
+		"kind":       "VPC",
+		"metadata": map[string]any{
+			"name":   "my-vpc",
+			"labels": map[string]any{"network": "main"},
+		},
+	}}
+	gateway := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "ec2.aws.upbound.io/v1beta1",
+		"kind":       "InternetGateway",
+		"metadata": map[string]any{
+			"name": "my-vpc",
+		},
+	}}
+	subnet := func(name string, forProvider map[string]any) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "ec2.aws.upbound.io/v1beta1",
+			"kind":       "Subnet",
+			"metadata": map[string]any{
+				"name": name,
+			},
+			"spec": map[string]any{
+				"forProvider": forProvider,
+			},
+		}}
+	}
+
+	cases := map[string]struct {
+		reason    string
+		resources map[resource.Name]*unstructured.Unstructured
+		want      []Dependency
+	}{
+		"NoReferences": {
+			reason: "Resources without references have no dependencies",
+			resources: map[resource.Name]*unstructured.Unstructured{
+				"vpc":    vpc,
+				"subnet": subnet("my-subnet", map[string]any{"region": "us-east-1"}),
+			},
+			want: []Dependency{},
+		},
+		"Ref": {
+			reason: "A resolved *Ref field should be a dependency",
+			resources: map[resource.Name]*unstructured.Unstructured{
+				"vpc":    vpc,
+				"subnet": subnet("my-subnet", map[string]any{"vpcIdRef": map[string]any{"name": "my-vpc"}}),
+			},
+			want: []Dependency{{Of: "vpc", By: "subnet"}},
+		},
+		"RefToExternalResource": {
+			reason: "A reference to a resource outside the composition is not a dependency",
+			resources: map[resource.Name]*unstructured.Unstructured{
+				"vpc":    vpc,
+				"subnet": subnet("my-subnet", map[string]any{"vpcIdRef": map[string]any{"name": "other-vpc"}}),
+			},
+			want: []Dependency{},
+		},
+		"Refs": {
+			reason: "Each resolved reference in a *Refs field should be a dependency",
+			resources: map[resource.Name]*unstructured.Unstructured{
+				"subnet-a": subnet("subnet-a", nil),
+				"subnet-b": subnet("subnet-b", nil),
+				"subnet-c": subnet("subnet-c", map[string]any{
+					"subnetIdRefs": []any{
+						map[string]any{"name": "subnet-a"},
+						map[string]any{"name": "subnet-b"},
+					},
+				}),
+			},
+			want: []Dependency{{Of: "subnet-a", By: "subnet-c"}, {Of: "subnet-b", By: "subnet-c"}},
+		},
+		"NestedRef": {
+			reason: "References nested in lists of objects should be dependencies",
+			resources: map[resource.Name]*unstructured.Unstructured{
+				"vpc": vpc,
+				"subnet": subnet("my-subnet", map[string]any{
+					"route": []any{
+						map[string]any{"vpcIdRef": map[string]any{"name": "my-vpc"}},
+					},
+				}),
+			},
+			want: []Dependency{{Of: "vpc", By: "subnet"}},
+		},
+		"AmbiguousRef": {
+			reason: "The field name should choose between resources of different kinds with the same name",
+			resources: map[resource.Name]*unstructured.Unstructured{
+				"vpc":     vpc,
+				"gateway": gateway,
+				"subnet":  subnet("my-subnet", map[string]any{"vpcIdRef": map[string]any{"name": "my-vpc"}}),
+			},
+			want: []Dependency{{Of: "vpc", By: "subnet"}},
+		},
+		"SelectorMatchControllerRef": {
+			reason: "An unresolved selector matching the controller should depend on resources of the referenced kind",
+			resources: map[resource.Name]*unstructured.Unstructured{
+				"vpc":     vpc,
+				"gateway": gateway,
+				"subnet":  subnet("my-subnet", map[string]any{"vpcIdSelector": map[string]any{"matchControllerRef": true}}),
+			},
+			want: []Dependency{{Of: "vpc", By: "subnet"}},
+		},
+		"SelectorKindSuffix": {
+			reason: "A selector should match kinds that end with the field's prefix",
+			resources: map[resource.Name]*unstructured.Unstructured{
+				"gateway": gateway,
+				"subnet":  subnet("my-subnet", map[string]any{"gatewayIdSelector": map[string]any{"matchControllerRef": true}}),
+			},
+			want: []Dependency{{Of: "gateway", By: "subnet"}},
+		},
+		"SelectorMatchLabels": {
+			reason: "A selector's labels must match the referenced resource",
+			resources: map[resource.Name]*unstructured.Unstructured{
+				"vpc":      vpc,
+				"subnet-a": subnet("subnet-a", map[string]any{"vpcIdSelector": map[string]any{"matchLabels": map[string]any{"network": "main"}}}),
+				"subnet-b": subnet("subnet-b", map[string]any{"vpcIdSelector": map[string]any{"matchLabels": map[string]any{"network": "other"}}}),
+			},
+			want: []Dependency{{Of: "vpc", By: "subnet-a"}},
+		},
+		"SelectorWithoutCriteria": {
+			reason: "A selector without criteria could select anything and is not a dependency",
+			resources: map[resource.Name]*unstructured.Unstructured{
+				"vpc":    vpc,
+				"subnet": subnet("my-subnet", map[string]any{"vpcIdSelector": map[string]any{}}),
+			},
+			want: []Dependency{},
+		},
+		"ResolvedSelector": {
+			reason: "A selector should be ignored once its reference is resolved",
+			resources: map[resource.Name]*unstructured.Unstructured{
+				"subnet-a": subnet("subnet-a", nil),
+				"subnet-b": subnet("subnet-b", nil),
+				"subnet-c": subnet("subnet-c", map[string]any{
+					"subnetIdRef":      map[string]any{"name": "subnet-a"},
+					"subnetIdSelector": map[string]any{"matchControllerRef": true},
+				}),
+			},
+			want: []Dependency{{Of: "subnet-a", By: "subnet-c"}},
+		},
+		"NoSelfReference": {
+			reason: "A resource should never depend on itself",
+			resources: map[resource.Name]*unstructured.Unstructured{
+				"subnet": subnet("my-subnet", map[string]any{"subnetIdSelector": map[string]any{"matchControllerRef": true}}),
+			},
+			want: []Dependency{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := InferDependencies(tc.resources)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nInferDependencies(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReferenceKind(t *testing.T) {
+	cases := map[string]struct {
+		prefix string
+		want   string
+	}{
+		"Id":     {prefix: "vpcId", want: "vpc"},
+		"Ids":    {prefix: "securityGroupIds", want: "securitygroup"},
+		"Arn":    {prefix: "roleArn", want: "role"},
+		"Name":   {prefix: "bucketName", want: "bucket"},
+		"Plain":  {prefix: "cluster", want: "cluster"},
+		"OnlyId": {prefix: "Id", want: "id"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := referenceKind(tc.prefix); got != tc.want {
+				t.Errorf("referenceKind(%q): want %q, got %q", tc.prefix, tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// An Expiry records a resource whose deletion protection has expired.
+type Expiry struct {
+	// Resource is the kind and name of the resource.
+	Resource string
+	// At is when the protection expired.
+	At time.Time
+}
+
+// ProtectionExpiry returns when the protection label or annotation of a
+// resource expires. Multiple objects may be supplied for the same resource,
+// for example its desired and observed state. The expiry is read from the
+// block-deletion-until annotation of any of the objects, or else their label,
+// and is either an RFC3339 time or a duration from the supplied creation time.
+// It returns false if the resource has no valid expiry, in which case its
+// protection doesn't expire.
+func ProtectionExpiry(created time.Time, objs ...*unstructured.Unstructured) (time.Time, bool) {
+	for _, u := range objs {
+		if u == nil || u.Object == nil {
+			continue
+		}
+		if v, ok := u.GetAnnotations()[ProtectionBlockDeletionUntil]; ok {
+			return parseExpiry(v, created)
+		}
+	}
+	for _, u := range objs {
+		if u == nil || u.Object == nil {
+			continue
+		}
+		if v, ok := u.GetLabels()[ProtectionBlockDeletionUntil]; ok {
+			return parseExpiry(v, created)
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseExpiry parses an RFC3339 time, or a duration from the supplied
+// creation time. It returns false if the value isn't valid.
+func parseExpiry(v string, created time.Time) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, true
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || created.IsZero() {
+		return time.Time{}, false
+	}
+	return created.Add(d), true
+}
+
+// creationTime returns the earliest creation timestamp of the objects.
+// Desired objects don't have a creation timestamp.
+func creationTime(objs []*unstructured.Unstructured) time.Time {
+	var created time.Time
+	for _, u := range objs {
+		if u == nil || u.Object == nil {
+			continue
+		}
+		t := u.GetCreationTimestamp().Time
+		if !t.IsZero() && (created.IsZero() || t.Before(created)) {
+			created = t
+		}
+	}
+	return created
+}
+
+// SetTime sets the time protection expiry is evaluated at. Defaults to the
+// current time.
+func (p *Policy) SetTime(now time.Time) {
+	p.now = now
+}
+
+// time returns the time protection expiry is evaluated at.
+func (p *Policy) time() time.Time {
+	if p.now.IsZero() {
+		return time.Now()
+	}
+	return p.now
+}
+
+// expire records that the protection of a resource expired.
+func (p *Policy) expire(name resource.Name, objs []*unstructured.Unstructured, at time.Time) {
+	kind, n := "", string(name)
+	for _, u := range objs {
+		if u == nil || u.Object == nil {
+			continue
+		}
+		if kind == "" {
+			kind = u.GetKind()
+		}
+		if u.GetName() != "" {
+			kind, n = u.GetKind(), u.GetName()
+			break
+		}
+	}
+	if p.expired == nil {
+		p.expired = map[string]time.Time{}
+	}
+	p.expired[strings.TrimSpace(kind+" "+n)] = at
+}
+
+// Expired returns the resources whose protection has expired, sorted by
+// resource.
+func (p *Policy) Expired() []Expiry {
+	e := make([]Expiry, 0, len(p.expired))
+	for r, at := range p.expired {
+		e = append(e, Expiry{Resource: r, At: at})
+	}
+	slices.SortFunc(e, func(a, b Expiry) int { return strings.Compare(a.Resource, b.Resource) })
+	return e
+}
+
+// NextExpiry returns the earliest time the protection of a protected resource
+// expires. It is zero if no protection expires.
+func (p *Policy) NextExpiry() time.Time {
+	return p.nextExpiry
+}
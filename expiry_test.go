@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+func TestProtectionExpiry(t *testing.T) {
+	created := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	withExpiry := func(labels, annotations map[string]any) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"metadata": map[string]any{"labels": labels, "annotations": annotations},
+		}}
+	}
+
+	type want struct {
+		at time.Time
+		ok bool
+	}
+	cases := map[string]struct {
+		reason  string
+		u       *unstructured.Unstructured
+		created time.Time
+		want    want
+	}{
+		"NoExpiry": {
+			reason:  "Protection without an expiry should never expire",
+			u:       withExpiry(nil, nil),
+			created: created,
+			want:    want{},
+		},
+		"Time": {
+			reason:  "An RFC3339 time should be the expiry",
+			u:       withExpiry(nil, map[string]any{ProtectionBlockDeletionUntil: "2026-12-31T00:00:00Z"}),
+			created: created,
+			want:    want{at: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC), ok: true},
+		},
+		"DurationLabel": {
+			reason:  "A duration in a label should expire relative to the creation time",
+			u:       withExpiry(map[string]any{ProtectionBlockDeletionUntil: "720h"}, nil),
+			created: created,
+			want:    want{at: created.Add(720 * time.Hour), ok: true},
+		},
+		"AnnotationPrecedence": {
+			reason:  "The annotation should take precedence over the label",
+			u:       withExpiry(map[string]any{ProtectionBlockDeletionUntil: "720h"}, map[string]any{ProtectionBlockDeletionUntil: "24h"}),
+			created: created,
+			want:    want{at: created.Add(24 * time.Hour), ok: true},
+		},
+		"DurationNotCreated": {
+			reason: "A duration shouldn't expire a resource that hasn't been created",
+			u:      withExpiry(nil, map[string]any{ProtectionBlockDeletionUntil: "24h"}),
+			want:   want{},
+		},
+		"Invalid": {
+			reason:  "An invalid expiry should be ignored, so the resource remains protected",
+			u:       withExpiry(nil, map[string]any{ProtectionBlockDeletionUntil: "next week"}),
+			created: created,
+			want:    want{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			at, ok := ProtectionExpiry(tc.created, tc.u)
+			if diff := cmp.Diff(tc.want, want{at: at, ok: ok}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nProtectionExpiry(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRunFunctionProtectionExpiry(t *testing.T) {
+	// The label is set on both the desired and observed bucket by default.
+	labels := `"labels": {
+		"protection.fn.crossplane.io/block-deletion": "true",
+		"protection.fn.crossplane.io/block-deletion-until": "336h"
+	}`
+	req := func(observedMeta, desiredMeta string) *fnv1.RunFunctionRequest {
+		xr := resource.MustStructJSON(`{
+			"apiVersion": "test.crossplane.io/v1",
+			"kind": "TestXR",
+			"metadata": {
+				"name": "my-test-xr"
+			}
+		}`)
+		return &fnv1.RunFunctionRequest{
+			Input: resource.MustStructJSON(`{
+				"apiVersion": "protection.fn.crossplane.io/v1beta1",
+				"kind": "Input",
+				"cacheTTL": "1h"
+			}`),
+			Observed: &fnv1.State{
+				Composite: &fnv1.Resource{Resource: xr},
+				Resources: map[string]*fnv1.Resource{
+					"bucket": {Resource: resource.MustStructJSON(`{
+						"apiVersion": "s3.aws.upbound.io/v1beta1",
+						"kind": "Bucket",
+						"metadata": {
+							"name": "my-bucket",
+							"creationTimestamp": "2026-10-01T00:00:00Z",
+							` + observedMeta + `
+						}
+					}`)},
+				},
+			},
+			Desired: &fnv1.State{
+				Composite: &fnv1.Resource{Resource: xr},
+				Resources: map[string]*fnv1.Resource{
+					"bucket": {Resource: resource.MustStructJSON(`{
+						"apiVersion": "s3.aws.upbound.io/v1beta1",
+						"kind": "Bucket",
+						"metadata": {
+							` + desiredMeta + `
+						}
+					}`)},
+				},
+			},
+		}
+	}
+
+	type want struct {
+		usages  int
+		ttl     time.Duration
+		results []string
+	}
+	// The label is only in the Composition, and the expiry is only
+	// annotated on the live resource.
+	annotated := `"annotations": {"protection.fn.crossplane.io/block-deletion-until": "336h"}`
+	unlabeled := `"labels": {}`
+	desiredLabel := `"labels": {"protection.fn.crossplane.io/block-deletion": "true"}`
+
+	cases := map[string]struct {
+		reason       string
+		now          string
+		observedMeta string
+		desiredMeta  string
+		want         want
+	}{
+		"NotExpired": {
+			reason:       "A Usage should be generated until protection expires, and the response should expire with it",
+			now:          "2026-10-14T23:30:00Z",
+			observedMeta: labels,
+			desiredMeta:  labels,
+			want: want{
+				usages:  2,
+				ttl:     30 * time.Minute,
+				results: []string{"Deletion of the composite and 1 composed resource is blocked by Usages"},
+			},
+		},
+		"Expired": {
+			reason:       "No Usage should be generated once protection expires, and the expiry should be reported",
+			now:          "2026-10-16T00:00:00Z",
+			observedMeta: labels,
+			desiredMeta:  labels,
+			want: want{
+				usages:  0,
+				ttl:     time.Hour,
+				results: []string{"Deletion protection of Bucket my-bucket expired at 2026-10-15T00:00:00Z"},
+			},
+		},
+		"ObservedAnnotationNotExpired": {
+			reason:       "An expiry annotated on the observed resource should apply to a label set in the Composition",
+			now:          "2026-10-14T23:30:00Z",
+			observedMeta: unlabeled + ", " + annotated,
+			desiredMeta:  desiredLabel,
+			want: want{
+				usages:  2,
+				ttl:     30 * time.Minute,
+				results: []string{"Deletion of the composite and 1 composed resource is blocked by Usages"},
+			},
+		},
+		"ObservedAnnotationExpired": {
+			reason:       "Protection by a label set in the Composition should expire at the time annotated on the observed resource",
+			now:          "2026-10-16T00:00:00Z",
+			observedMeta: unlabeled + ", " + annotated,
+			desiredMeta:  desiredLabel,
+			want: want{
+				usages:  0,
+				ttl:     time.Hour,
+				results: []string{"Deletion protection of Bucket my-bucket expired at 2026-10-15T00:00:00Z"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			now, _ := time.Parse(time.RFC3339, tc.now)
+			f := &Function{log: logging.NewNopLogger(), now: func() time.Time { return now }}
+			rsp, err := f.RunFunction(context.Background(), req(tc.observedMeta, tc.desiredMeta))
+			if err != nil {
+				t.Fatalf("%s\nf.RunFunction(...): %v", tc.reason, err)
+			}
+			// The bucket itself is always desired.
+			if diff := cmp.Diff(tc.want.usages, len(rsp.GetDesired().GetResources())-1); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want usages, +got usages:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.ttl, rsp.GetMeta().GetTtl().AsDuration()); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want ttl, +got ttl:\n%s", tc.reason, diff)
+			}
+			results := []string{}
+			for _, r := range rsp.GetResults() {
+				results = append(results, r.GetMessage())
+			}
+			if diff := cmp.Diff(tc.want.results, results); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want results, +got results:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
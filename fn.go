@@ -3,8 +3,8 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"maps"
+	"slices"
 	"strings"
 	"time"
 
@@ -26,17 +26,36 @@ import (
 type Function struct {
 	fnv1.UnimplementedFunctionRunnerServiceServer
 
-	log logging.Logger
+	log     logging.Logger
+	metrics *Metrics
+
+	// now returns the current time. Defaults to time.Now.
+	now func() time.Time
 }
 
 const (
 	ProtectionLabelBlockDeletion           = "protection.fn.crossplane.io/block-deletion"
+	ProtectionAnnotationReason             = "protection.fn.crossplane.io/reason"
+	ProtectionBlockDeletionUntil           = "protection.fn.crossplane.io/block-deletion-until"
+	ProtectionAnnotationAllowDeletion      = "protection.fn.crossplane.io/allow-deletion"
+	ProtectionAnnotationAllowDeletionUntil = "protection.fn.crossplane.io/allow-deletion-until"
 	ProtectionGroupVersion                 = protectionv1beta1.Group + "/" + protectionv1beta1.Version
 	ProtectionReason                       = "created by function-deletion-protection "
 	ProtectionReasonLabel                  = ProtectionReason + "via label " + ProtectionLabelBlockDeletion
+	ProtectionReasonRule                   = ProtectionReason + "via rule"
+	ProtectionReasonResourceName           = ProtectionReason + "via resource name in the function input"
+	ProtectionReasonExpression             = ProtectionReason + "via expression"
 	ProtectionReasonCompositeChildResource = ProtectionReason + "because a composed resource is protected"
+	ProtectionReasonComposite              = ProtectionReason + "because its composite is protected"
+	ProtectionReasonClaim                  = ProtectionReason + "because the composite resource it claims is protected"
+	ProtectionReasonNestedComposite        = ProtectionReason + "because a nested composite resource is protected"
+	ProtectionReasonProviderConfig         = ProtectionReason + "because a protected resource uses this ProviderConfig"
+	ProtectionReasonConnectionSecret       = ProtectionReason + "because a protected resource writes its connection details to it"
+	ProtectionReasonEnvironmentConfig      = ProtectionReason + "because the composite of a protected resource uses it"
 	ProtectionReasonOperation              = ProtectionReason + "by an Operation"
 	ProtectionReasonWatchOperation         = ProtectionReason + "by a WatchOperation"
+	ProtectionReasonDependency             = ProtectionReason + "because a composed resource depends on it"
+	ProtectionReasonDeletionOrder          = ProtectionReason + "to order the deletion of composed resources"
 	ProtectionV1GroupVersion               = apiextensionsv1beta1.Group + "/" + apiextensionsv1beta1.Version
 	// UsageNameSuffix is the suffix applied when generating Usage names.
 	UsageNameSuffix = "fn-protection"
@@ -47,250 +66,639 @@ const (
 // RunFunction runs the Function.
 func (f *Function) RunFunction(_ context.Context, req *fnv1.RunFunctionRequest) (*fnv1.RunFunctionResponse, error) {
 	f.log.Info("Running function", "tag", req.GetMeta().GetTag())
+	defer f.metrics.ObserveRequest(Capability(req), time.Now())
 
 	rsp := response.To(req, response.DefaultTTL)
 
 	in := &v1beta1.Input{}
 	if err := request.GetInput(req, in); err != nil {
-		response.Fatal(rsp, errors.Wrapf(err, "cannot get Function input from %T", req))
+		f.fatal(rsp, "input", errors.Wrapf(err, "cannot get Function input from %T", req))
 		return rsp, nil
 	}
 	if in.CacheTTL != "" {
 		dur, err := time.ParseDuration(in.CacheTTL)
 		if err != nil {
-			response.Fatal(rsp, errors.Wrapf(err, "cannot set cacheTTL"))
+			f.fatal(rsp, "cache-ttl", errors.Wrapf(err, "cannot set cacheTTL"))
 			return rsp, nil
 		}
 		rsp.Meta.Ttl = durationpb.New(dur)
 	}
 
+	var statusFields []string
+	if in.StatusFieldPath != "" {
+		fields, err := ParseStatusFieldPath(in.StatusFieldPath)
+		if err != nil {
+			f.fatal(rsp, "status-field-path", errors.Wrap(err, "cannot parse statusFieldPath"))
+			return rsp, nil
+		}
+		statusFields = fields
+	}
+
+	if err := ValidateDeletionOrder(in.DeletionOrder); err != nil {
+		f.fatal(rsp, "deletion-order", errors.Wrap(err, "cannot parse deletionOrder"))
+		return rsp, nil
+	}
+
 	desiredComposite, err := request.GetDesiredCompositeResource(req)
 	if err != nil {
-		response.Fatal(rsp, errors.Wrap(err, "cannot get desired composite"))
+		f.fatal(rsp, "desired-composite", errors.Wrap(err, "cannot get desired composite"))
 		return rsp, nil
 	}
 
 	observedComposite, err := request.GetObservedCompositeResource(req)
 	if err != nil {
-		response.Fatal(rsp, errors.Wrap(err, "cannot get observed composite"))
+		f.fatal(rsp, "observed-composite", errors.Wrap(err, "cannot get observed composite"))
+		return rsp, nil
+	}
+
+	policy, err := NewPolicy(in, &observedComposite.Resource.Unstructured)
+	if err != nil {
+		f.fatal(rsp, "policy", errors.Wrap(err, "cannot build protection policy"))
+		return rsp, nil
+	}
+
+	windows, err := NewProtectionWindows(in.ProtectionWindows)
+	if err != nil {
+		f.fatal(rsp, "protection-windows", errors.Wrap(err, "cannot parse protection windows"))
 		return rsp, nil
 	}
+	now := f.time()
+	policy.SetTime(now)
+	if len(windows) > 0 {
+		ws := windows.Evaluate(now)
+		f.log.Debug("evaluated protection windows", "active", ws.Active, "window", ws.Window, "next", ws.Next)
+		// Run again when a window starts or ends.
+		shortenTTL(rsp, now, ws.Next)
+		if !ws.Active {
+			policy.Suspend()
+			msg := "Deletion protection is inactive outside of protection windows"
+			if !ws.Next.IsZero() {
+				msg += " until " + ws.Next.UTC().Format(time.RFC3339)
+			}
+			response.Normal(rsp, msg)
+		}
+	}
+
+	usageAPI, why := SelectUsageAPI(in, req)
+	f.log.Debug("selected usage API", "api", usageAPI, "reason", why)
+	gen, err := NewUsageGenerator(in, usageAPI, observedComposite.Resource.GetName())
+	if err != nil {
+		f.fatal(rsp, "usage-templates", errors.Wrap(err, "cannot build usage generator"))
+		return rsp, nil
+	}
+	gen.Now = now
 
 	observedComposed, err := request.GetObservedComposedResources(req)
 	if err != nil {
-		response.Fatal(rsp, errors.Wrap(err, "cannot get observed resources"))
+		f.fatal(rsp, "observed-composed", errors.Wrap(err, "cannot get observed resources"))
 		return rsp, nil
 	}
 
 	desiredComposed, err := request.GetDesiredComposedResources(req)
 	if err != nil {
-		response.Fatal(rsp, errors.Wrapf(err, "cannot get desired composed resources from %T", req))
+		f.fatal(rsp, "desired-composed", errors.Wrapf(err, "cannot get desired composed resources from %T", req))
 		return rsp, nil
 	}
 
 	// Process Composed Resources
 	var protectedCount int
-	composedUsages, err := f.ProtectComposedResources(desiredComposed, observedComposed, in.EnableV1Mode)
+	usages := map[resource.Name]*resource.DesiredComposed{}
+	// Composed resources inherit the protection of the composite when
+	// protection propagates down, unless deletion of the composite is allowed.
+	var inherit bool
+	if policy.PropagatesDown() {
+		_, inherit = policy.Evaluate("", &observedComposite.Resource.Unstructured, &desiredComposite.Resource.Unstructured)
+		if _, _, allowed := AllowDeletion(&observedComposite.Resource.Unstructured, now); allowed {
+			inherit = false
+		}
+	}
+	composedUsages, propagated, err := f.ProtectComposedResources(desiredComposed, observedComposed, inherit, policy, gen)
 	if err != nil {
-		response.Fatal(rsp, errors.Wrap(err, "cannot process composed resources"))
+		f.fatal(rsp, "composed-resources", errors.Wrap(err, "cannot process composed resources"))
+		return rsp, nil
+	}
+
+	// Dependencies are found before any Usages are added to the desired
+	// composed resources, so a Usage is never ordered after another Usage.
+	resources := dependencyResources(desiredComposed, observedComposed, policy)
+
+	// Order deletion of composed resources that reference each other.
+	var inferred []Dependency
+	if in.InferDependencies {
+		inferred = InferDependencies(resources)
+		dependencyUsages, err := f.ProtectDependencies(inferred, resources, ProtectionReasonDependency, policy, gen)
+		if err != nil {
+			f.fatal(rsp, "dependencies", errors.Wrap(err, "cannot process composed resource dependencies"))
+			return rsp, nil
+		}
+		if err := MergeDesired(desiredComposed, dependencyUsages); err != nil {
+			f.fatal(rsp, "merge-usages", err)
+			return rsp, nil
+		}
+		maps.Copy(usages, dependencyUsages)
+	}
+
+	// Delete composed resources in the order set by the Input. Inferred
+	// dependencies take precedence over an order that reverses them.
+	if len(in.DeletionOrder) > 0 {
+		order, dropped := RemoveCycles(OrderDependencies(in.DeletionOrder, resources), inferred)
+		for _, d := range dropped {
+			response.Warning(rsp, errors.Errorf("cannot delete %q before %q as set by deletionOrder: %q must be deleted first because of inferred dependencies", d.By, d.Of, d.Of))
+		}
+		orderUsages, err := f.ProtectDependencies(order, resources, ProtectionReasonDeletionOrder, policy, gen)
+		if err != nil {
+			f.fatal(rsp, "deletion-order", errors.Wrap(err, "cannot process deletion order"))
+			return rsp, nil
+		}
+		if err := MergeDesired(desiredComposed, orderUsages); err != nil {
+			f.fatal(rsp, "merge-usages", err)
+			return rsp, nil
+		}
+		maps.Copy(usages, orderUsages)
+	}
+	if err := MergeDesired(desiredComposed, composedUsages); err != nil {
+		f.fatal(rsp, "merge-usages", err)
 		return rsp, nil
 	}
-	maps.Copy(desiredComposed, composedUsages)
+	maps.Copy(usages, composedUsages)
 	protectedCount += len(composedUsages)
 
+	// Protect the resources that protected composed resources depend on.
+	if in.ProtectSupportingResources {
+		supportingUsages, err := f.ProtectSupportingResources(ProtectedResources(composedUsages, observedComposed), observedComposed, &observedComposite.Resource.Unstructured, in.ProviderConfigAPIVersions, policy, gen)
+		if err != nil {
+			f.fatal(rsp, "supporting-resources", errors.Wrap(err, "cannot protect supporting resources"))
+			return rsp, nil
+		}
+		if err := MergeDesired(desiredComposed, supportingUsages); err != nil {
+			f.fatal(rsp, "merge-usages", err)
+			return rsp, nil
+		}
+		maps.Copy(usages, supportingUsages)
+		protectedCount += len(supportingUsages)
+	}
+
 	// Create a Usage on the Composite:
-	// - If any resources in the Composition are being protected
+	// - If any protected resources in the Composition propagate protection
 	// - If the Composite has the label
-	compositeUsage, err := f.ProtectComposite(observedComposite, desiredComposite, protectedCount, in.EnableV1Mode)
+	compositeUsage, err := f.ProtectComposite(observedComposite, desiredComposite, propagated, policy, gen)
 	if err != nil {
-		response.Fatal(rsp, errors.Wrap(err, "cannot protect composite resource"))
+		f.fatal(rsp, "composite", errors.Wrap(err, "cannot protect composite resource"))
 		return rsp, nil
 	}
 	if compositeUsage != nil {
-		maps.Copy(desiredComposed, compositeUsage)
+		if err := MergeDesired(desiredComposed, compositeUsage); err != nil {
+			f.fatal(rsp, "merge-usages", err)
+			return rsp, nil
+		}
+		maps.Copy(usages, compositeUsage)
 		protectedCount++
+
+		// Deleting the claim or parent composite deletes the composite.
+		ownerUsages, err := f.ProtectCompositeOwners(observedComposite, desiredComposite, policy, gen)
+		if err != nil {
+			f.fatal(rsp, "composite-owners", errors.Wrap(err, "cannot protect claim and parent composite"))
+			return rsp, nil
+		}
+		if err := MergeDesired(desiredComposed, ownerUsages); err != nil {
+			f.fatal(rsp, "merge-usages", err)
+			return rsp, nil
+		}
+		maps.Copy(usages, ownerUsages)
+		protectedCount += len(ownerUsages)
+	}
+
+	// Operations don't have a composite to report protection on.
+	if observedComposite.Resource.GetKind() != "" {
+		composedProtected := ProtectedResources(composedUsages, observedComposed)
+		SetDeletionProtectedCondition(rsp, compositeUsage, composedProtected)
+
+		if statusFields != nil {
+			status := NewProtectionStatus(compositeUsage, composedProtected)
+			if err := SetProtectionStatus(observedComposite, desiredComposite, statusFields, status); err != nil {
+				response.Warning(rsp, errors.Wrapf(err, "cannot write protection status to %s", in.StatusFieldPath))
+			} else if err := response.SetDesiredCompositeResource(rsp, desiredComposite); err != nil {
+				f.fatal(rsp, "set-desired-composite", errors.Wrap(err, "cannot set desired composite"))
+				return rsp, nil
+			}
+		}
 	}
 
 	// Protect any required resources that are present.
 	requiredResources, err := request.GetRequiredResources(req)
 	if err != nil {
-		response.Fatal(rsp, errors.Wrap(err, "cannot get required resources"))
+		f.fatal(rsp, "required-resources", errors.Wrap(err, "cannot get required resources"))
 		return rsp, nil
 	}
 
+	// Usages previously created by Operations are required so that stale
+	// Usages can be reported. They are not protected themselves.
+	var existingUsages []resource.Required
+	var usagesFetched bool
+	if in.DetectStaleUsages {
+		rsp.Requirements = &fnv1.Requirements{Resources: UsageRequirements()}
+		existingUsages, usagesFetched = TakeUsages(requiredResources)
+	}
+
+	out := &OperationOutput{}
+	rr := map[resource.Name]*resource.DesiredComposed{}
 	if len(requiredResources) > 0 {
 		f.log.Debug("processing required resources")
-		rr, err := ProtectRequiredResources(requiredResources)
+		rr, out.Resources, err = ProtectRequiredResources(requiredResources, policy, gen)
 		if err != nil {
-			response.Fatal(rsp, errors.Wrap(err, "cannot process required resources"))
+			f.fatal(rsp, "protect-required-resources", errors.Wrap(err, "cannot process required resources"))
+			return rsp, nil
+		}
+		if err := MergeDesired(desiredComposed, rr); err != nil {
+			f.fatal(rsp, "merge-usages", err)
 			return rsp, nil
 		}
-		maps.Copy(desiredComposed, rr)
+		maps.Copy(usages, rr)
 		protectedCount += len(rr)
 	}
 
+	// Run again when protection expires, so its Usage is removed promptly.
+	for _, e := range policy.Expired() {
+		response.Normal(rsp, "Deletion protection of "+e.Resource+" expired at "+e.At.UTC().Format(time.RFC3339))
+	}
+	shortenTTL(rsp, now, policy.NextExpiry())
+
+	// v1 Usages can't protect namespaced resources, and deletion overrides
+	// drop Usages. Run again when an override expires to restore its Usage.
+	for _, err := range gen.Warnings() {
+		response.Warning(rsp, err)
+	}
+	for _, o := range gen.Overrides {
+		shortenTTL(rsp, now, o.Until)
+	}
+
+	if usagesFetched {
+		out.StaleUsages = FindStaleUsages(existingUsages, rr, requiredResources)
+		for _, u := range out.StaleUsages {
+			response.Warning(rsp, errors.Errorf("%s %s is stale: %s", u.Kind, u.Name, u.Reason))
+		}
+	}
+
+	// Report what happened to required resources, for example in the status
+	// of an Operation.
+	if len(out.Resources) > 0 || usagesFetched {
+		if err := SetOutput(rsp, out); err != nil {
+			f.fatal(rsp, "output", errors.Wrap(err, "cannot set output"))
+			return rsp, nil
+		}
+	}
+
 	if err := response.SetDesiredComposedResources(rsp, desiredComposed); err != nil {
-		response.Fatal(rsp, errors.Wrap(err, "cannot set desired resources"))
+		f.fatal(rsp, "set-desired-composed", errors.Wrap(err, "cannot set desired resources"))
 		return rsp, nil
 	}
 	f.log.Debug("usages created", "total", protectedCount)
+	f.metrics.ObserveUsages(usages)
 
 	return rsp, nil
 }
 
-// ProtectResource determines if a Resource requires deletion protection.
-func ProtectResource(u *unstructured.Unstructured) bool {
+// time returns the current time.
+func (f *Function) time() time.Time {
+	if f.now == nil {
+		return time.Now()
+	}
+	return f.now()
+}
+
+// shortenTTL caches the response until the supplied time, if it is sooner
+// than the response's TTL. A zero time doesn't change the TTL.
+func shortenTTL(rsp *fnv1.RunFunctionResponse, now, until time.Time) {
+	if !until.IsZero() && until.Sub(now) < rsp.GetMeta().GetTtl().AsDuration() {
+		rsp.Meta.Ttl = durationpb.New(until.Sub(now))
+	}
+}
+
+// fatal sets a Fatal result and records the error path it was returned from.
+func (f *Function) fatal(rsp *fnv1.RunFunctionResponse, path string, err error) {
+	f.metrics.ObserveFatal(path)
+	response.Fatal(rsp, err)
+}
+
+// ProtectionMarkers are the labels and annotations that mark a resource as
+// protected, and the values that enable protection.
+type ProtectionMarkers struct {
+	LabelKeys      []string
+	AnnotationKeys []string
+	Values         []string
+}
+
+// DefaultProtectionMarkers returns the markers used when the Input doesn't
+// configure any.
+func DefaultProtectionMarkers() ProtectionMarkers {
+	return ProtectionMarkers{
+		LabelKeys: []string{ProtectionLabelBlockDeletion},
+		Values:    []string{"true"},
+	}
+}
+
+// accepts returns true if the value enables protection.
+func (m ProtectionMarkers) accepts(val string) bool {
+	for _, v := range m.Values {
+		if strings.EqualFold(val, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProtectResource determines if a resource requires deletion protection at
+// the supplied time. Multiple objects may be supplied for the same resource,
+// for example its desired and observed state. It returns a reason naming the
+// label or annotation that matched, and when the protection expires, which is
+// zero if it doesn't. Resources aren't protected once their protection has
+// expired.
+func ProtectResource(m ProtectionMarkers, now time.Time, objs ...*unstructured.Unstructured) (string, time.Time, bool) {
+	var reason string
+	var ok bool
+	for _, u := range objs {
+		if reason, ok = m.match(u); ok {
+			break
+		}
+	}
+	if !ok {
+		return "", time.Time{}, false
+	}
+	at, _ := ProtectionExpiry(creationTime(objs), objs...)
+	if !at.IsZero() && !now.Before(at) {
+		return "", at, false
+	}
+	return reason, at, true
+}
+
+// match returns a reason naming the label or annotation that marks the
+// resource as protected, if any.
+func (m ProtectionMarkers) match(u *unstructured.Unstructured) (string, bool) {
 	if u == nil || u.Object == nil {
-		return false
+		return "", false
 	}
 	labels := u.GetLabels()
-	val, ok := labels[ProtectionLabelBlockDeletion]
-	if ok && strings.EqualFold(val, "true") {
-		return true
+	for _, k := range m.LabelKeys {
+		if val, ok := labels[k]; ok && m.accepts(val) {
+			return LabelReason(k), true
+		}
 	}
-	return false
+	annotations := u.GetAnnotations()
+	for _, k := range m.AnnotationKeys {
+		if val, ok := annotations[k]; ok && m.accepts(val) {
+			return AnnotationReason(k), true
+		}
+	}
+	return "", false
 }
 
-// ProtectComposedResources creates Usages for Composed Resources.
-func (f *Function) ProtectComposedResources(desiredComposed map[resource.Name]*resource.DesiredComposed, observedComposed map[resource.Name]resource.ObservedComposed, enableV1Mode bool) (map[resource.Name]*resource.DesiredComposed, error) {
+// LabelReason returns the Usage reason for a resource protected by a label.
+func LabelReason(key string) string {
+	return ProtectionReason + "via label " + key
+}
+
+// AnnotationReason returns the Usage reason for a resource protected by an
+// annotation.
+func AnnotationReason(key string) string {
+	return ProtectionReason + "via annotation " + key
+}
+
+// ProtectComposedResources creates Usages for Composed Resources. If inherit
+// is true the composite is protected, and resources that don't opt out of
+// propagation inherit its protection. It also returns the number of protected
+// resources that propagate protection to the composite.
+func (f *Function) ProtectComposedResources(desiredComposed map[resource.Name]*resource.DesiredComposed, observedComposed map[resource.Name]resource.ObservedComposed, inherit bool, policy *Policy, g *UsageGenerator) (map[resource.Name]*resource.DesiredComposed, int, error) {
 	dc := map[resource.Name]*resource.DesiredComposed{}
+	var propagated int
 	for name, desired := range desiredComposed {
 		// A Usage will be created if there is an Observed Resource on the Cluster
-		if observed, ok := observedComposed[name]; ok {
-			// The label can either be defined in the pipeline or applied outside of Crossplane
-			if ProtectResource(&desired.Resource.Unstructured) || ProtectResource(&observed.Resource.Unstructured) {
-				f.log.Debug("protecting Composed resource", "kind", observed.Resource.GetKind(), "name", observed.Resource.GetName(), "namespace", observed.Resource.GetNamespace())
-				usage := GenerateUsage(&observed.Resource.Unstructured, ProtectionReasonLabel, enableV1Mode)
-				usageComposed := composed.New()
-				if err := convertViaJSON(usageComposed, usage); err != nil {
-					return dc, err
-				}
-				f.log.Debug("created usage", "kind", usageComposed.GetKind(), "name", usageComposed.GetName(), "namespace", usageComposed.GetNamespace())
-				dc[name+"-usage"] = &resource.DesiredComposed{Resource: usageComposed}
-			}
+		observed, ok := observedComposed[name]
+		if !ok {
+			continue
+		}
+		// The label can either be defined in the pipeline or applied outside of Crossplane
+		objs := []*unstructured.Unstructured{&desired.Resource.Unstructured, &observed.Resource.Unstructured}
+		reason, protect := policy.Evaluate(name, objs...)
+		optedOut := policy.OptedOut(objs...)
+		if !protect && inherit && !optedOut && !policy.Suspended() && !policy.Excluded(name, objs...) {
+			reason, protect = ProtectionReasonComposite, true
+		}
+		if !protect {
+			continue
+		}
+		f.log.Debug("protecting Composed resource", "kind", observed.Resource.GetKind(), "name", observed.Resource.GetName(), "namespace", observed.Resource.GetNamespace())
+		usage, ok, err := g.Generate(&observed.Resource.Unstructured, nil, reason, policy.ReplayDeletion(name, objs...))
+		if err != nil {
+			return dc, propagated, err
+		}
+		if !ok {
+			continue
+		}
+		usageComposed := composed.New()
+		if err := convertViaJSON(usageComposed, usage); err != nil {
+			return dc, propagated, err
+		}
+		key, err := g.Key(name+UsageResourceSuffix, &observed.Resource.Unstructured, nil)
+		if err != nil {
+			return dc, propagated, err
+		}
+		f.log.Debug("created usage", "kind", usageComposed.GetKind(), "name", usageComposed.GetName(), "namespace", usageComposed.GetNamespace())
+		if err := addUsage(dc, key, usageComposed); err != nil {
+			return dc, propagated, err
+		}
+		if policy.PropagatesUp() && reason != ProtectionReasonComposite && !optedOut {
+			propagated++
 		}
 	}
-	return dc, nil
+	return dc, propagated, nil
 }
 
 // ProtectComposite creates a Usage for the Composite Resource if it should be protected.
 // Protection occurs if:
-// - The composite has the protection label, or
-// - Any composed resources are being protected (protectedCount > 0).
-func (f *Function) ProtectComposite(observedComposite *resource.Composite, desiredComposite *resource.Composite, protectedCount int, enableV1Mode bool) (map[resource.Name]*resource.DesiredComposed, error) {
-	if !ProtectResource(&observedComposite.Resource.Unstructured) && !ProtectResource(&desiredComposite.Resource.Unstructured) && protectedCount == 0 {
+// - The composite has the protection label or matches a rule, or
+// - Any protected composed resources propagate protection to it (propagated > 0).
+func (f *Function) ProtectComposite(observedComposite *resource.Composite, desiredComposite *resource.Composite, propagated int, policy *Policy, g *UsageGenerator) (map[resource.Name]*resource.DesiredComposed, error) {
+	reason, protect := policy.Evaluate("", &observedComposite.Resource.Unstructured, &desiredComposite.Resource.Unstructured)
+	if !protect && propagated == 0 {
 		return nil, nil
 	}
 
 	f.log.Debug("protecting composite", "kind", observedComposite.Resource.GetKind(), "name", observedComposite.Resource.GetName(), "namespace", observedComposite.Resource.GetNamespace())
 
-	var reason string
-	if protectedCount > 0 {
+	if propagated > 0 {
 		reason = ProtectionReasonCompositeChildResource
-	} else {
-		reason = ProtectionReasonLabel
 	}
 
-	usage := GenerateUsage(&observedComposite.Resource.Unstructured, reason, enableV1Mode)
+	usage, ok, err := g.Generate(&observedComposite.Resource.Unstructured, nil, reason, policy.ReplayDeletion("", &observedComposite.Resource.Unstructured, &desiredComposite.Resource.Unstructured))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
 	usageComposed := composed.New()
 	if err := convertViaJSON(usageComposed, usage); err != nil {
 		return nil, errors.Wrap(err, "cannot convert usage to unstructured")
 	}
 
-	uname := strings.ToLower("xr-" + observedComposite.Resource.GetName() + "-usage")
+	uname, err := g.Key(resource.Name(strings.ToLower("xr-"+observedComposite.Resource.GetName()+UsageResourceSuffix)), &observedComposite.Resource.Unstructured, nil)
+	if err != nil {
+		return nil, err
+	}
 	f.log.Debug("creating usage", "kind", usageComposed.GetKind(), "name", usageComposed.GetName(), "namespace", usageComposed.GetNamespace())
 
 	return map[resource.Name]*resource.DesiredComposed{
-		resource.Name(uname): {Resource: usageComposed},
+		uname: {Resource: usageComposed},
 	}, nil
 }
 
 // ProtectRequiredResources creates usages for Required Resources in a Composition.
-// Usages are generated for any Watched resource. Other required resources need to have the label.
-func ProtectRequiredResources(rr map[string][]resource.Required) (map[resource.Name]*resource.DesiredComposed, error) {
+// Usages are generated for any Watched resource. Other required resources need to have the label
+// or match a rule. Exclude rules apply to all required resources, including Watched resources.
+// Usages are labeled with LabelOperationUsage so that stale Usages can be detected,
+// and keyed by their name.
+// A report is returned for every required resource, sorted by requirement name.
+func ProtectRequiredResources(rr map[string][]resource.Required, policy *Policy, g *UsageGenerator) (map[resource.Name]*resource.DesiredComposed, []RequiredResourceReport, error) {
 	dc := map[resource.Name]*resource.DesiredComposed{}
-	for resourceName, v := range rr {
-		for _, r := range v {
-			if resourceName == RequirementsNameWatchedResource || ProtectResource(r.Resource) {
-				var reason string
-				if resourceName == RequirementsNameWatchedResource {
+	reports := []RequiredResourceReport{}
+	for _, resourceName := range slices.Sorted(maps.Keys(rr)) {
+		for _, r := range rr[resourceName] {
+			report := RequiredResourceReport{
+				Requirement: resourceName,
+				Resource:    objectReference(r.Resource),
+			}
+			var reason string
+			if resourceName == RequirementsNameWatchedResource {
+				if !policy.Suspended() && !policy.Excluded("", r.Resource) {
 					reason = ProtectionReasonWatchOperation
-				} else {
-					reason = ProtectionReasonOperation
 				}
-				usage := GenerateV2Usage(r.Resource, reason)
+			} else if _, protect := policy.Evaluate("", r.Resource); protect {
+				reason = ProtectionReasonOperation
+			}
+			var usage map[string]any
+			if reason != "" {
+				var err error
+				if usage, _, err = g.Generate(r.Resource, nil, reason, policy.ReplayDeletion("", r.Resource)); err != nil {
+					return dc, reports, err
+				}
+			}
+			if usage != nil {
 				usageComposed := composed.New()
 				if err := convertViaJSON(usageComposed, usage); err != nil {
-					return dc, errors.Wrap(err, "cannot convert usage to unstructured")
+					return dc, reports, errors.Wrap(err, "cannot convert usage to unstructured")
+				}
+				labels := usageComposed.GetLabels()
+				labels[LabelOperationUsage] = "true"
+				usageComposed.SetLabels(labels)
+
+				// A resource may be required more than once. The Usage
+				// from the first requirement is used.
+				uname, err := g.Key(resource.Name(usageComposed.GetName()), r.Resource, nil)
+				if err != nil {
+					return dc, reports, err
+				}
+				if existing, ok := dc[uname]; ok {
+					usageComposed = existing.Resource
+					reason = usageReason(&existing.Resource.Unstructured)
 				}
-				uname := fmt.Sprintf("%s-%s-%s-required-resource-fn-protection", r.Resource.GetKind(), r.Resource.GetName(), r.Resource.GetNamespace())
-				dc[resource.Name(uname)] = &resource.DesiredComposed{Resource: usageComposed}
+				dc[uname] = &resource.DesiredComposed{Resource: usageComposed}
+
+				ref := objectReference(&usageComposed.Unstructured)
+				report.Protected = true
+				report.Reason = reason
+				report.Usage = &ref
 			}
+			reports = append(reports, report)
 		}
 	}
-	return dc, nil
+	return dc, reports, nil
 }
 
 // GenerateUsage determines whether to return a v1 or v2 Crossplane usage.
-func GenerateUsage(u *unstructured.Unstructured, reason string, createV1Usages bool) map[string]any {
+// If by is not nil the Usage blocks deletion of u until by is deleted.
+func GenerateUsage(u, by *unstructured.Unstructured, reason string, replayDeletion, createV1Usages bool) map[string]any {
 	if createV1Usages {
-		return GenerateV1Usage(u, reason)
+		return GenerateV1Usage(u, by, reason, replayDeletion)
+	}
+	return GenerateV2Usage(u, by, reason, replayDeletion)
+}
+
+// usageResourceRef returns a Usage's reference to a resource.
+func usageResourceRef(u *unstructured.Unstructured) map[string]any {
+	return map[string]any{
+		"apiVersion": u.GetAPIVersion(),
+		"kind":       u.GetKind(),
+		"resourceRef": map[string]any{
+			"name": u.GetName(),
+		},
 	}
-	return GenerateV2Usage(u, reason)
 }
 
-// GenerateV2Usage creates a v2 Usage for a resource.
-func GenerateV2Usage(u *unstructured.Unstructured, reason string) map[string]any {
-	name := strings.ToLower(u.GetKind() + "-" + u.GetName())
+// GenerateV2Usage creates a v2 Usage for a resource. If by is namespaced the
+// Usage is created in the namespace of by. Otherwise it is created in the
+// namespace of u, and a namespaced Usage may be used by a cluster scoped
+// resource.
+func GenerateV2Usage(u, by *unstructured.Unstructured, reason string, replayDeletion bool) map[string]any {
 	usageType := protectionv1beta1.ClusterUsageKind
 	usageMeta := map[string]any{
-		"name": GenerateName(name, UsageNameSuffix),
+		"name":   usageName(u, by),
+		"labels": usageLabels(u, reason),
 	}
 
+	of := usageResourceRef(u)
 	namespace := u.GetNamespace()
+	if by != nil && by.GetNamespace() != "" {
+		namespace = by.GetNamespace()
+	}
 	if namespace != "" {
 		usageType = protectionv1beta1.UsageKind
 		usageMeta["namespace"] = namespace
 	}
+	if u.GetNamespace() != "" && u.GetNamespace() != namespace {
+		of["resourceRef"].(map[string]any)["namespace"] = u.GetNamespace()
+	}
+
+	spec := map[string]any{
+		"of":     of,
+		"reason": reason,
+	}
+	if by != nil {
+		spec["by"] = usageResourceRef(by)
+	}
+	if replayDeletion {
+		spec["replayDeletion"] = true
+	}
 
 	usage := map[string]any{
 		"apiVersion": ProtectionGroupVersion,
 		"kind":       usageType,
 		"metadata":   usageMeta,
-		"spec": map[string]any{
-			"of": map[string]any{
-				"apiVersion": u.GetAPIVersion(),
-				"kind":       u.GetKind(),
-				"resourceRef": map[string]any{
-					"name": u.GetName(),
-				},
-			},
-			"reason": reason,
-		},
+		"spec":       spec,
 	}
 	return usage
 }
 
 // GenerateV1Usage creates a Crossplane v1 Usage for a resource.
 // Only Cluster Scoped Resources are supported.
-func GenerateV1Usage(u *unstructured.Unstructured, reason string) map[string]any {
-	name := strings.ToLower(u.GetKind() + "-" + u.GetName())
+func GenerateV1Usage(u, by *unstructured.Unstructured, reason string, replayDeletion bool) map[string]any {
+	spec := map[string]any{
+		"of":     usageResourceRef(u),
+		"reason": reason,
+	}
+	if by != nil {
+		spec["by"] = usageResourceRef(by)
+	}
+	if replayDeletion {
+		spec["replayDeletion"] = true
+	}
 	usage := map[string]any{
 		"apiVersion": ProtectionV1GroupVersion,
 		"kind":       apiextensionsv1beta1.UsageKind,
 		"metadata": map[string]any{
-			"name": GenerateName(name, UsageNameSuffix),
-		},
-		"spec": map[string]any{
-			"of": map[string]any{
-				"apiVersion": u.GetAPIVersion(),
-				"kind":       u.GetKind(),
-				"resourceRef": map[string]any{
-					"name": u.GetName(),
-				},
-			},
-			"reason": reason,
+			"name":   usageName(u, by),
+			"labels": usageLabels(u, reason),
 		},
+		"spec": spec,
 	}
 	return usage
 }
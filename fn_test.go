@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/testing/protocmp"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -143,10 +144,18 @@ func TestRunFunction(t *testing.T) {
 			},
 			want: want{
 				rsp: &fnv1.RunFunctionResponse{
-					Desired:    &fnv1.State{},
-					Meta:       &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
-					Results:    []*fnv1.Result{},
-					Conditions: []*fnv1.Condition{},
+					Desired: &fnv1.State{},
+					Meta:    &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    ConditionTypeDeletionProtected,
+							Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+							Reason:  ConditionReasonNotProtected,
+							Message: proto.String("No resources are protected by function-deletion-protection"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
 				},
 			},
 		},
@@ -265,7 +274,14 @@ func TestRunFunction(t *testing.T) {
 									"apiVersion": "protection.crossplane.io/v1beta1",
 									"kind": "ClusterUsage",
 									"metadata": {
-										"name": "testxr-my-test-xr-23c942-fn-protection"
+										"name": "testxr-my-test-xr-fd1fd4-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestXR",
+											"protection.fn.crossplane.io/protected-name": "my-test-xr",
+											"protection.fn.crossplane.io/reason-category": "label"
+										}
 									},
 									"spec": {
 										"of": {
@@ -281,9 +297,23 @@ func TestRunFunction(t *testing.T) {
 							},
 						},
 					},
-					Meta:       &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
-					Results:    []*fnv1.Result{},
-					Conditions: []*fnv1.Condition{},
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "Deletion of the composite is blocked by a Usage",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    ConditionTypeDeletionProtected,
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  ConditionReasonProtected,
+							Message: proto.String("Composite is protected via label protection.fn.crossplane.io/block-deletion"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
 				},
 			},
 		},
@@ -408,8 +438,15 @@ func TestRunFunction(t *testing.T) {
 									"apiVersion": "protection.crossplane.io/v1beta1",
 									"kind": "Usage",
 									"metadata": {
-										"name": "testxr-my-test-xr-23c942-fn-protection",
-										"namespace": "test"
+										"name": "testxr-my-test-xr-55c26a-fn-protection",
+										"namespace": "test",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestXR",
+											"protection.fn.crossplane.io/protected-name": "my-test-xr",
+											"protection.fn.crossplane.io/reason-category": "label"
+										}
 									},
 									"spec": {
 										"of": {
@@ -425,9 +462,23 @@ func TestRunFunction(t *testing.T) {
 							},
 						},
 					},
-					Meta:       &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
-					Results:    []*fnv1.Result{},
-					Conditions: []*fnv1.Condition{},
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "Deletion of the composite is blocked by a Usage",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    ConditionTypeDeletionProtected,
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  ConditionReasonProtected,
+							Message: proto.String("Composite is protected via label protection.fn.crossplane.io/block-deletion"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
 				},
 			},
 		},
@@ -547,7 +598,14 @@ func TestRunFunction(t *testing.T) {
 									"apiVersion": "protection.crossplane.io/v1beta1",
 									"kind": "ClusterUsage",
 									"metadata": {
-										"name": "testxr-my-test-xr-23c942-fn-protection"
+										"name": "testxr-my-test-xr-fd1fd4-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestXR",
+											"protection.fn.crossplane.io/protected-name": "my-test-xr",
+											"protection.fn.crossplane.io/reason-category": "composed-resource"
+										}
 									},
 									"spec": {
 										"of": {
@@ -566,7 +624,14 @@ func TestRunFunction(t *testing.T) {
 									"apiVersion": "protection.crossplane.io/v1beta1",
 									"kind": "ClusterUsage",
 									"metadata": {
-										"name": "testcomposed-my-test-composed-601ab8-fn-protection"
+										"name": "testcomposed-my-test-composed-403eb9-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestComposed",
+											"protection.fn.crossplane.io/protected-name": "my-test-composed",
+											"protection.fn.crossplane.io/reason-category": "label"
+										}
 									},
 									"spec": {
 										"of": {
@@ -582,9 +647,23 @@ func TestRunFunction(t *testing.T) {
 							},
 						},
 					},
-					Meta:       &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
-					Results:    []*fnv1.Result{},
-					Conditions: []*fnv1.Condition{},
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "Deletion of the composite and 1 composed resource is blocked by Usages",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    ConditionTypeDeletionProtected,
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  ConditionReasonProtected,
+							Message: proto.String("Composite is protected because a composed resource is protected. Protected composed resources: ready-composed-resource (via label protection.fn.crossplane.io/block-deletion)"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
 				},
 			},
 		},
@@ -701,7 +780,14 @@ func TestRunFunction(t *testing.T) {
 									"apiVersion": "protection.crossplane.io/v1beta1",
 									"kind": "ClusterUsage",
 									"metadata": {
-										"name": "testxr-my-test-xr-23c942-fn-protection"
+										"name": "testxr-my-test-xr-fd1fd4-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestXR",
+											"protection.fn.crossplane.io/protected-name": "my-test-xr",
+											"protection.fn.crossplane.io/reason-category": "composed-resource"
+										}
 									},
 									"spec": {
 										"of": {
@@ -720,7 +806,14 @@ func TestRunFunction(t *testing.T) {
 									"apiVersion": "protection.crossplane.io/v1beta1",
 									"kind": "ClusterUsage",
 									"metadata": {
-										"name": "testcomposed-my-test-composed-601ab8-fn-protection"
+										"name": "testcomposed-my-test-composed-403eb9-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestComposed",
+											"protection.fn.crossplane.io/protected-name": "my-test-composed",
+											"protection.fn.crossplane.io/reason-category": "label"
+										}
 									},
 									"spec": {
 										"of": {
@@ -736,9 +829,23 @@ func TestRunFunction(t *testing.T) {
 							},
 						},
 					},
-					Meta:       &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
-					Results:    []*fnv1.Result{},
-					Conditions: []*fnv1.Condition{},
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "Deletion of the composite and 1 composed resource is blocked by Usages",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    ConditionTypeDeletionProtected,
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  ConditionReasonProtected,
+							Message: proto.String("Composite is protected because a composed resource is protected. Protected composed resources: ready-composed-resource (via label protection.fn.crossplane.io/block-deletion)"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
 				},
 			},
 		},
@@ -864,8 +971,15 @@ func TestRunFunction(t *testing.T) {
 									"apiVersion": "protection.crossplane.io/v1beta1",
 									"kind": "Usage",
 									"metadata": {
-										"name": "testxr-my-test-xr-23c942-fn-protection",
-										"namespace": "test"
+										"name": "testxr-my-test-xr-55c26a-fn-protection",
+										"namespace": "test",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestXR",
+											"protection.fn.crossplane.io/protected-name": "my-test-xr",
+											"protection.fn.crossplane.io/reason-category": "composed-resource"
+										}
 									},
 									"spec": {
 										"of": {
@@ -884,8 +998,15 @@ func TestRunFunction(t *testing.T) {
 									"apiVersion": "protection.crossplane.io/v1beta1",
 									"kind": "Usage",
 									"metadata": {
-										"name": "testcomposed-my-test-composed-601ab8-fn-protection",
-										"namespace": "test"
+										"name": "testcomposed-my-test-composed-0f6bf9-fn-protection",
+										"namespace": "test",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestComposed",
+											"protection.fn.crossplane.io/protected-name": "my-test-composed",
+											"protection.fn.crossplane.io/reason-category": "label"
+										}
 									},
 									"spec": {
 										"of": {
@@ -901,35 +1022,43 @@ func TestRunFunction(t *testing.T) {
 							},
 						},
 					},
-					Meta:       &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
-					Results:    []*fnv1.Result{},
-					Conditions: []*fnv1.Condition{},
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "Deletion of the composite and 1 composed resource is blocked by Usages",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    ConditionTypeDeletionProtected,
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  ConditionReasonProtected,
+							Message: proto.String("Composite is protected because a composed resource is protected. Protected composed resources: ready-composed-resource (via label protection.fn.crossplane.io/block-deletion)"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
 				},
 			},
 		},
-		"ProtectCompositeResourceWithV1Usage": {
-			reason: "V1 Usage Created for a Composite when EnableV1Mode is true",
+		"ProtectComposedResourceByRule": {
+			reason: "Usages Created for a Composed resource matched by a rule in the Input",
 			args: args{
 				req: &fnv1.RunFunctionRequest{
 					Meta: &fnv1.RequestMeta{Tag: "hello"},
 					Input: resource.MustStructJSON(`{
 						"apiVersion": "template.fn.crossplane.io/v1beta1",
 						"kind": "Input",
-						"enableV1Mode": true
+						"rules": [
+							{
+								"name": "test-composed",
+								"apiVersion": "test.crossplane.io/*",
+								"kind": "TestComposed"
+							}
+						]
 					}`),
 					Desired: &fnv1.State{
-						Composite: &fnv1.Resource{
-							Resource: resource.MustStructJSON(`{
-								"apiVersion": "test.crossplane.io/v1",
-								"kind": "TestXR",
-								"metadata": {
-									"name": "my-test-xr",
-									"labels": {
-										"protection.fn.crossplane.io/block-deletion": "true"
-									}
-								}
-							}`),
-						},
 						Resources: map[string]*fnv1.Resource{
 							"ready-composed-resource": {
 								Resource: resource.MustStructJSON(`{
@@ -937,15 +1066,6 @@ func TestRunFunction(t *testing.T) {
 									"kind": "TestComposed",
 									"metadata": {
 										"name": "my-test-composed"
-									},
-									"spec": {},
-									"status": {
-										"conditions": [
-											{
-												"type": "Ready",
-												"status": "True"
-											}
-										]
 									}
 								}`),
 							},
@@ -968,15 +1088,6 @@ func TestRunFunction(t *testing.T) {
 									"kind": "TestComposed",
 									"metadata": {
 										"name": "my-test-composed"
-									},
-									"spec": {},
-									"status": {
-										"conditions": [
-											{
-												"type": "Ready",
-												"status": "True"
-											}
-										]
 									}
 								}`),
 							},
@@ -987,18 +1098,6 @@ func TestRunFunction(t *testing.T) {
 			want: want{
 				rsp: &fnv1.RunFunctionResponse{
 					Desired: &fnv1.State{
-						Composite: &fnv1.Resource{
-							Resource: resource.MustStructJSON(`{
-								"apiVersion": "test.crossplane.io/v1",
-								"kind": "TestXR",
-								"metadata": {
-									"name": "my-test-xr",
-									"labels": {
-										"protection.fn.crossplane.io/block-deletion": "true"
-									}
-								}
-							}`),
-						},
 						Resources: map[string]*fnv1.Resource{
 							"ready-composed-resource": {
 								Resource: resource.MustStructJSON(`{
@@ -1006,24 +1105,48 @@ func TestRunFunction(t *testing.T) {
 									"kind": "TestComposed",
 									"metadata": {
 										"name": "my-test-composed"
+									}
+								}`),
+							},
+							"ready-composed-resource-usage": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "protection.crossplane.io/v1beta1",
+									"kind": "ClusterUsage",
+									"metadata": {
+										"name": "testcomposed-my-test-composed-403eb9-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestComposed",
+											"protection.fn.crossplane.io/protected-name": "my-test-composed",
+											"protection.fn.crossplane.io/reason-category": "rule"
+										}
 									},
-									"spec": {},
-									"status": {
-										"conditions": [
-											{
-												"type": "Ready",
-												"status": "True"
+									"spec": {
+										"of": {
+											"apiVersion": "test.crossplane.io/v1",
+											"kind": "TestComposed",
+											"resourceRef": {
+												"name": "my-test-composed"
 											}
-										]
+										},
+										"reason": "created by function-deletion-protection via rule test-composed"
 									}
 								}`),
 							},
 							"xr-my-test-xr-usage": {
 								Resource: resource.MustStructJSON(`{
-									"apiVersion": "apiextensions.crossplane.io/v1beta1",
-									"kind": "Usage",
+									"apiVersion": "protection.crossplane.io/v1beta1",
+									"kind": "ClusterUsage",
 									"metadata": {
-										"name": "testxr-my-test-xr-23c942-fn-protection"
+										"name": "testxr-my-test-xr-fd1fd4-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestXR",
+											"protection.fn.crossplane.io/protected-name": "my-test-xr",
+											"protection.fn.crossplane.io/reason-category": "composed-resource"
+										}
 									},
 									"spec": {
 										"of": {
@@ -1033,57 +1156,50 @@ func TestRunFunction(t *testing.T) {
 												"name": "my-test-xr"
 											}
 										},
-										"reason": "created by function-deletion-protection via label protection.fn.crossplane.io/block-deletion"
+										"reason": "created by function-deletion-protection because a composed resource is protected"
 									}
 								}`),
 							},
 						},
 					},
-					Meta:       &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
-					Results:    []*fnv1.Result{},
-					Conditions: []*fnv1.Condition{},
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "Deletion of the composite and 1 composed resource is blocked by Usages",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    ConditionTypeDeletionProtected,
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  ConditionReasonProtected,
+							Message: proto.String("Composite is protected because a composed resource is protected. Protected composed resources: ready-composed-resource (via rule test-composed)"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
 				},
 			},
 		},
-		"ProtectCompositeResourceWithV1UsageWhenProtectedResourcesExist": {
-			reason: "V1 Usage Created for a Composite when CreateV1Usages is true and protected composed resources exist",
+		"ProtectComposedResourceByName": {
+			reason: "Usages Created for a Composed resource matched by a protected resource name in the Input",
 			args: args{
 				req: &fnv1.RunFunctionRequest{
 					Meta: &fnv1.RequestMeta{Tag: "hello"},
 					Input: resource.MustStructJSON(`{
 						"apiVersion": "template.fn.crossplane.io/v1beta1",
 						"kind": "Input",
-						"enableV1Mode": true
+						"protectedResourceNames": ["ready-*"]
 					}`),
 					Desired: &fnv1.State{
-						Composite: &fnv1.Resource{
-							Resource: resource.MustStructJSON(`{
-								"apiVersion": "test.crossplane.io/v1",
-								"kind": "TestXR",
-								"metadata": {
-									"name": "my-test-xr"
-								}
-							}`),
-						},
 						Resources: map[string]*fnv1.Resource{
 							"ready-composed-resource": {
 								Resource: resource.MustStructJSON(`{
 									"apiVersion": "test.crossplane.io/v1",
 									"kind": "TestComposed",
 									"metadata": {
-										"name": "my-test-composed",
-										"labels": {
-											"protection.fn.crossplane.io/block-deletion": "true"
-										}
-									},
-									"spec": {},
-									"status": {
-										"conditions": [
-											{
-												"type": "Ready",
-												"status": "True"
-											}
-										]
+										"name": "my-test-composed"
 									}
 								}`),
 							},
@@ -1106,15 +1222,6 @@ func TestRunFunction(t *testing.T) {
 									"kind": "TestComposed",
 									"metadata": {
 										"name": "my-test-composed"
-									},
-									"spec": {},
-									"status": {
-										"conditions": [
-											{
-												"type": "Ready",
-												"status": "True"
-											}
-										]
 									}
 								}`),
 							},
@@ -1125,43 +1232,29 @@ func TestRunFunction(t *testing.T) {
 			want: want{
 				rsp: &fnv1.RunFunctionResponse{
 					Desired: &fnv1.State{
-						Composite: &fnv1.Resource{
-							Resource: resource.MustStructJSON(`{
-								"apiVersion": "test.crossplane.io/v1",
-								"kind": "TestXR",
-								"metadata": {
-									"name": "my-test-xr"
-								}
-							}`),
-						},
 						Resources: map[string]*fnv1.Resource{
 							"ready-composed-resource": {
 								Resource: resource.MustStructJSON(`{
 									"apiVersion": "test.crossplane.io/v1",
 									"kind": "TestComposed",
 									"metadata": {
-										"name": "my-test-composed",
-										"labels": {
-											"protection.fn.crossplane.io/block-deletion": "true"
-										}
-									},
-									"spec": {},
-									"status": {
-										"conditions": [
-											{
-												"type": "Ready",
-												"status": "True"
-											}
-										]
+										"name": "my-test-composed"
 									}
 								}`),
 							},
 							"ready-composed-resource-usage": {
 								Resource: resource.MustStructJSON(`{
-									"apiVersion": "apiextensions.crossplane.io/v1beta1",
-									"kind": "Usage",
+									"apiVersion": "protection.crossplane.io/v1beta1",
+									"kind": "ClusterUsage",
 									"metadata": {
-										"name": "testcomposed-my-test-composed-601ab8-fn-protection"
+										"name": "testcomposed-my-test-composed-403eb9-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestComposed",
+											"protection.fn.crossplane.io/protected-name": "my-test-composed",
+											"protection.fn.crossplane.io/reason-category": "resource-name"
+										}
 									},
 									"spec": {
 										"of": {
@@ -1171,16 +1264,23 @@ func TestRunFunction(t *testing.T) {
 												"name": "my-test-composed"
 											}
 										},
-										"reason": "created by function-deletion-protection via label protection.fn.crossplane.io/block-deletion"
+										"reason": "created by function-deletion-protection via resource name in the function input"
 									}
 								}`),
 							},
 							"xr-my-test-xr-usage": {
 								Resource: resource.MustStructJSON(`{
-									"apiVersion": "apiextensions.crossplane.io/v1beta1",
-									"kind": "Usage",
+									"apiVersion": "protection.crossplane.io/v1beta1",
+									"kind": "ClusterUsage",
 									"metadata": {
-										"name": "testxr-my-test-xr-23c942-fn-protection"
+										"name": "testxr-my-test-xr-fd1fd4-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestXR",
+											"protection.fn.crossplane.io/protected-name": "my-test-xr",
+											"protection.fn.crossplane.io/reason-category": "composed-resource"
+										}
 									},
 									"spec": {
 										"of": {
@@ -1196,13 +1296,1090 @@ func TestRunFunction(t *testing.T) {
 							},
 						},
 					},
-					Meta:       &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
-					Results:    []*fnv1.Result{},
-					Conditions: []*fnv1.Condition{},
-				},
-			},
-		},
-	}
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "Deletion of the composite and 1 composed resource is blocked by Usages",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    ConditionTypeDeletionProtected,
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  ConditionReasonProtected,
+							Message: proto.String("Composite is protected because a composed resource is protected. Protected composed resources: ready-composed-resource (via resource name in the function input)"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"InvalidRule": {
+			reason: "The Function should return a Fatal result if a rule cannot be compiled",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"rules": [
+							{
+								"name": "broken",
+								"kind": "[TestComposed"
+							}
+						]
+					}`),
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{
+						{
+							Message:  "cannot build protection policy: cannot compile rule 0 \"broken\": invalid pattern \"[TestComposed\": syntax error in pattern",
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{},
+				},
+			},
+		},
+		"ProtectComposedResourceByExpression": {
+			reason: "Usages Created for a Composed resource matched by a CEL expression in the Input",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"expressions": [
+							{
+								"name": "us-east-1",
+								"expression": "object.spec.region == \"us-east-1\" && composite.metadata.name == \"my-test-xr\""
+							}
+						]
+					}`),
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"ready-composed-resource": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "test.crossplane.io/v1",
+									"kind": "TestComposed",
+									"metadata": {
+										"name": "my-test-composed"
+									},
+									"spec": {
+										"region": "us-east-1"
+									}
+								}`),
+							},
+						},
+					},
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"apiVersion": "test.crossplane.io/v1",
+								"kind": "TestXR",
+								"metadata": {
+									"name": "my-test-xr"
+								}
+							}`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"ready-composed-resource": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "test.crossplane.io/v1",
+									"kind": "TestComposed",
+									"metadata": {
+										"name": "my-test-composed"
+									},
+									"spec": {
+										"region": "us-east-1"
+									}
+								}`),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"ready-composed-resource": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "test.crossplane.io/v1",
+									"kind": "TestComposed",
+									"metadata": {
+										"name": "my-test-composed"
+									},
+									"spec": {
+										"region": "us-east-1"
+									}
+								}`),
+							},
+							"ready-composed-resource-usage": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "protection.crossplane.io/v1beta1",
+									"kind": "ClusterUsage",
+									"metadata": {
+										"name": "testcomposed-my-test-composed-403eb9-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestComposed",
+											"protection.fn.crossplane.io/protected-name": "my-test-composed",
+											"protection.fn.crossplane.io/reason-category": "expression"
+										}
+									},
+									"spec": {
+										"of": {
+											"apiVersion": "test.crossplane.io/v1",
+											"kind": "TestComposed",
+											"resourceRef": {
+												"name": "my-test-composed"
+											}
+										},
+										"reason": "created by function-deletion-protection via expression us-east-1"
+									}
+								}`),
+							},
+							"xr-my-test-xr-usage": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "protection.crossplane.io/v1beta1",
+									"kind": "ClusterUsage",
+									"metadata": {
+										"name": "testxr-my-test-xr-fd1fd4-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestXR",
+											"protection.fn.crossplane.io/protected-name": "my-test-xr",
+											"protection.fn.crossplane.io/reason-category": "composed-resource"
+										}
+									},
+									"spec": {
+										"of": {
+											"apiVersion": "test.crossplane.io/v1",
+											"kind": "TestXR",
+											"resourceRef": {
+												"name": "my-test-xr"
+											}
+										},
+										"reason": "created by function-deletion-protection because a composed resource is protected"
+									}
+								}`),
+							},
+						},
+					},
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "Deletion of the composite and 1 composed resource is blocked by Usages",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    ConditionTypeDeletionProtected,
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  ConditionReasonProtected,
+							Message: proto.String("Composite is protected because a composed resource is protected. Protected composed resources: ready-composed-resource (via expression us-east-1)"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"InvalidExpression": {
+			reason: "The Function should return a Fatal result naming an expression that cannot be compiled",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"expressions": [
+							{
+								"expression": "\"us-east-1\""
+							}
+						]
+					}`),
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{
+						{
+							Message:  "cannot build protection policy: expression \"\\\"us-east-1\\\"\" must evaluate to a bool, not string",
+							Severity: fnv1.Severity_SEVERITY_FATAL,
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{},
+				},
+			},
+		},
+		"InferDependencies": {
+			reason: "Usages with spec.by are created between Composed resources that reference each other",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"inferDependencies": true
+					}`),
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"vpc": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "ec2.aws.m.upbound.io/v1beta1",
+									"kind": "VPC",
+									"metadata": {
+										"namespace": "my-namespace"
+									}
+								}`),
+							},
+							"subnet": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "ec2.aws.m.upbound.io/v1beta1",
+									"kind": "Subnet",
+									"metadata": {
+										"namespace": "my-namespace"
+									},
+									"spec": {
+										"forProvider": {
+											"vpcIdSelector": {
+												"matchControllerRef": true
+											}
+										}
+									}
+								}`),
+							},
+						},
+					},
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"apiVersion": "test.crossplane.io/v1",
+								"kind": "TestXR",
+								"metadata": {
+									"name": "my-test-xr",
+									"namespace": "my-namespace"
+								}
+							}`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"vpc": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "ec2.aws.m.upbound.io/v1beta1",
+									"kind": "VPC",
+									"metadata": {
+										"name": "my-vpc",
+										"namespace": "my-namespace"
+									}
+								}`),
+							},
+							"subnet": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "ec2.aws.m.upbound.io/v1beta1",
+									"kind": "Subnet",
+									"metadata": {
+										"name": "my-subnet",
+										"namespace": "my-namespace"
+									},
+									"spec": {
+										"forProvider": {
+											"vpcIdRef": {
+												"name": "my-vpc"
+											},
+											"vpcIdSelector": {
+												"matchControllerRef": true
+											}
+										}
+									}
+								}`),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"vpc": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "ec2.aws.m.upbound.io/v1beta1",
+									"kind": "VPC",
+									"metadata": {
+										"namespace": "my-namespace"
+									}
+								}`),
+							},
+							"subnet": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "ec2.aws.m.upbound.io/v1beta1",
+									"kind": "Subnet",
+									"metadata": {
+										"namespace": "my-namespace"
+									},
+									"spec": {
+										"forProvider": {
+											"vpcIdSelector": {
+												"matchControllerRef": true
+											}
+										}
+									}
+								}`),
+							},
+							"subnet-vpc-a590d1-dependency-usage": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "protection.crossplane.io/v1beta1",
+									"kind": "Usage",
+									"metadata": {
+										"name": "vpc-my-vpc-subnet-my-subnet-5f657c-fn-protection",
+										"namespace": "my-namespace",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "VPC",
+											"protection.fn.crossplane.io/protected-name": "my-vpc",
+											"protection.fn.crossplane.io/reason-category": "dependency"
+										}
+									},
+									"spec": {
+										"of": {
+											"apiVersion": "ec2.aws.m.upbound.io/v1beta1",
+											"kind": "VPC",
+											"resourceRef": {
+												"name": "my-vpc"
+											}
+										},
+										"by": {
+											"apiVersion": "ec2.aws.m.upbound.io/v1beta1",
+											"kind": "Subnet",
+											"resourceRef": {
+												"name": "my-subnet"
+											}
+										},
+										"reason": "created by function-deletion-protection because a composed resource depends on it"
+									}
+								}`),
+							},
+						},
+					},
+					Meta:    &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    ConditionTypeDeletionProtected,
+							Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+							Reason:  ConditionReasonNotProtected,
+							Message: proto.String("No resources are protected by function-deletion-protection"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"DetectStaleUsages": {
+			reason: "Usages created by Operations should be required, and stale Usages reported",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"detectStaleUsages": true
+					}`),
+					RequiredResources: map[string]*fnv1.Resources{
+						RequirementsNameWatchedResource: {
+							Items: []*fnv1.Resource{{
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "v1",
+									"kind": "Namespace",
+									"metadata": {
+										"name": "prod"
+									}
+								}`),
+							}},
+						},
+						RequirementsNameUsages: {},
+						RequirementsNameClusterUsages: {
+							Items: []*fnv1.Resource{{
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "protection.crossplane.io/v1beta1",
+									"kind": "ClusterUsage",
+									"metadata": {
+										"name": "namespace-dev-66a9db-fn-protection",
+										"labels": {
+											"protection.fn.crossplane.io/operation-usage": "true"
+										}
+									},
+									"spec": {
+										"of": {
+											"apiVersion": "v1",
+											"kind": "Namespace",
+											"resourceRef": {
+												"name": "dev"
+											}
+										},
+										"reason": "created by function-deletion-protection by a WatchOperation"
+									}
+								}`),
+							}},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"namespace-prod-c8f0b8-fn-protection": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "protection.crossplane.io/v1beta1",
+									"kind": "ClusterUsage",
+									"metadata": {
+										"name": "namespace-prod-c8f0b8-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/operation-usage": "true",
+											"protection.fn.crossplane.io/protected-kind": "Namespace",
+											"protection.fn.crossplane.io/protected-name": "prod",
+											"protection.fn.crossplane.io/reason-category": "watch-operation"
+										}
+									},
+									"spec": {
+										"of": {
+											"apiVersion": "v1",
+											"kind": "Namespace",
+											"resourceRef": {
+												"name": "prod"
+											}
+										},
+										"reason": "created by function-deletion-protection by a WatchOperation"
+									}
+								}`),
+							},
+						},
+					},
+					Requirements: &fnv1.Requirements{Resources: UsageRequirements()},
+					Meta:         &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  "ClusterUsage namespace-dev-66a9db-fn-protection is stale: target not found",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Output: resource.MustStructJSON(`{
+						"resources": [
+							{
+								"requirement": "ops.crossplane.io/watched-resource",
+								"resource": {
+									"apiVersion": "v1",
+									"kind": "Namespace",
+									"name": "prod"
+								},
+								"protected": true,
+								"reason": "created by function-deletion-protection by a WatchOperation",
+								"usage": {
+									"apiVersion": "protection.crossplane.io/v1beta1",
+									"kind": "ClusterUsage",
+									"name": "namespace-prod-c8f0b8-fn-protection"
+								}
+							}
+						],
+						"staleUsages": [
+							{
+								"apiVersion": "protection.crossplane.io/v1beta1",
+								"kind": "ClusterUsage",
+								"name": "namespace-dev-66a9db-fn-protection",
+								"of": {
+									"apiVersion": "v1",
+									"kind": "Namespace",
+									"name": "dev"
+								},
+								"reason": "target not found"
+							}
+						]
+					}`),
+					Conditions: []*fnv1.Condition{},
+				},
+			},
+		},
+		"V1UsageOfNamespacedResource": {
+			reason: "A Warning should be returned instead of a v1 Usage of a namespaced resource",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"usageAPI": "v1"
+					}`),
+					RequiredResources: map[string]*fnv1.Resources{
+						RequirementsNameWatchedResource: {
+							Items: []*fnv1.Resource{{
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "v1",
+									"kind": "ConfigMap",
+									"metadata": {
+										"name": "config",
+										"namespace": "prod"
+									}
+								}`),
+							}},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{},
+					},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_WARNING,
+							Message:  "cannot protect namespaced ConfigMap prod/config with a v1 Usage: set usageAPI to v2 or auto",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Output: resource.MustStructJSON(`{
+						"resources": [
+							{
+								"requirement": "ops.crossplane.io/watched-resource",
+								"resource": {
+									"apiVersion": "v1",
+									"kind": "ConfigMap",
+									"name": "config",
+									"namespace": "prod"
+								},
+								"protected": false
+							}
+						]
+					}`),
+					Conditions: []*fnv1.Condition{},
+				},
+			},
+		},
+		"WriteProtectionStatus": {
+			reason: "A summary of the protected resources should be written to the composite's status",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"statusFieldPath": "status.deletionProtection",
+						"rules": [
+							{
+								"name": "test-composed",
+								"apiVersion": "test.crossplane.io/*",
+								"kind": "TestComposed"
+							}
+						]
+					}`),
+					Desired: &fnv1.State{
+						Resources: map[string]*fnv1.Resource{
+							"ready-composed-resource": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "test.crossplane.io/v1",
+									"kind": "TestComposed",
+									"metadata": {
+										"name": "my-test-composed"
+									}
+								}`),
+							},
+						},
+					},
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"apiVersion": "test.crossplane.io/v1",
+								"kind": "TestXR",
+								"metadata": {
+									"name": "my-test-xr"
+								}
+							}`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"ready-composed-resource": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "test.crossplane.io/v1",
+									"kind": "TestComposed",
+									"metadata": {
+										"name": "my-test-composed"
+									}
+								}`),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"status": {
+									"deletionProtection": {
+										"protected": true,
+										"count": 2,
+										"composite": {
+											"usage": "testxr-my-test-xr-fd1fd4-fn-protection",
+											"reason": "created by function-deletion-protection because a composed resource is protected"
+										},
+										"resources": [
+											{
+												"name": "ready-composed-resource",
+												"usage": "testcomposed-my-test-composed-403eb9-fn-protection",
+												"reason": "created by function-deletion-protection via rule test-composed"
+											}
+										]
+									}
+								}
+							}`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"ready-composed-resource": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "test.crossplane.io/v1",
+									"kind": "TestComposed",
+									"metadata": {
+										"name": "my-test-composed"
+									}
+								}`),
+							},
+							"ready-composed-resource-usage": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "protection.crossplane.io/v1beta1",
+									"kind": "ClusterUsage",
+									"metadata": {
+										"name": "testcomposed-my-test-composed-403eb9-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestComposed",
+											"protection.fn.crossplane.io/protected-name": "my-test-composed",
+											"protection.fn.crossplane.io/reason-category": "rule"
+										}
+									},
+									"spec": {
+										"of": {
+											"apiVersion": "test.crossplane.io/v1",
+											"kind": "TestComposed",
+											"resourceRef": {
+												"name": "my-test-composed"
+											}
+										},
+										"reason": "created by function-deletion-protection via rule test-composed"
+									}
+								}`),
+							},
+							"xr-my-test-xr-usage": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "protection.crossplane.io/v1beta1",
+									"kind": "ClusterUsage",
+									"metadata": {
+										"name": "testxr-my-test-xr-fd1fd4-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestXR",
+											"protection.fn.crossplane.io/protected-name": "my-test-xr",
+											"protection.fn.crossplane.io/reason-category": "composed-resource"
+										}
+									},
+									"spec": {
+										"of": {
+											"apiVersion": "test.crossplane.io/v1",
+											"kind": "TestXR",
+											"resourceRef": {
+												"name": "my-test-xr"
+											}
+										},
+										"reason": "created by function-deletion-protection because a composed resource is protected"
+									}
+								}`),
+							},
+						},
+					},
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "Deletion of the composite and 1 composed resource is blocked by Usages",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    ConditionTypeDeletionProtected,
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  ConditionReasonProtected,
+							Message: proto.String("Composite is protected because a composed resource is protected. Protected composed resources: ready-composed-resource (via rule test-composed)"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"ProtectCompositeResourceWithV1Usage": {
+			reason: "V1 Usage Created for a Composite when EnableV1Mode is true",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"enableV1Mode": true
+					}`),
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"apiVersion": "test.crossplane.io/v1",
+								"kind": "TestXR",
+								"metadata": {
+									"name": "my-test-xr",
+									"labels": {
+										"protection.fn.crossplane.io/block-deletion": "true"
+									}
+								}
+							}`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"ready-composed-resource": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "test.crossplane.io/v1",
+									"kind": "TestComposed",
+									"metadata": {
+										"name": "my-test-composed"
+									},
+									"spec": {},
+									"status": {
+										"conditions": [
+											{
+												"type": "Ready",
+												"status": "True"
+											}
+										]
+									}
+								}`),
+							},
+						},
+					},
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"apiVersion": "test.crossplane.io/v1",
+								"kind": "TestXR",
+								"metadata": {
+									"name": "my-test-xr"
+								}
+							}`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"ready-composed-resource": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "test.crossplane.io/v1",
+									"kind": "TestComposed",
+									"metadata": {
+										"name": "my-test-composed"
+									},
+									"spec": {},
+									"status": {
+										"conditions": [
+											{
+												"type": "Ready",
+												"status": "True"
+											}
+										]
+									}
+								}`),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"apiVersion": "test.crossplane.io/v1",
+								"kind": "TestXR",
+								"metadata": {
+									"name": "my-test-xr",
+									"labels": {
+										"protection.fn.crossplane.io/block-deletion": "true"
+									}
+								}
+							}`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"ready-composed-resource": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "test.crossplane.io/v1",
+									"kind": "TestComposed",
+									"metadata": {
+										"name": "my-test-composed"
+									},
+									"spec": {},
+									"status": {
+										"conditions": [
+											{
+												"type": "Ready",
+												"status": "True"
+											}
+										]
+									}
+								}`),
+							},
+							"xr-my-test-xr-usage": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "apiextensions.crossplane.io/v1beta1",
+									"kind": "Usage",
+									"metadata": {
+										"name": "testxr-my-test-xr-fd1fd4-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestXR",
+											"protection.fn.crossplane.io/protected-name": "my-test-xr",
+											"protection.fn.crossplane.io/reason-category": "label"
+										}
+									},
+									"spec": {
+										"of": {
+											"apiVersion": "test.crossplane.io/v1",
+											"kind": "TestXR",
+											"resourceRef": {
+												"name": "my-test-xr"
+											}
+										},
+										"reason": "created by function-deletion-protection via label protection.fn.crossplane.io/block-deletion"
+									}
+								}`),
+							},
+						},
+					},
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "Deletion of the composite is blocked by a Usage",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    ConditionTypeDeletionProtected,
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  ConditionReasonProtected,
+							Message: proto.String("Composite is protected via label protection.fn.crossplane.io/block-deletion"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+		"ProtectCompositeResourceWithV1UsageWhenProtectedResourcesExist": {
+			reason: "V1 Usage Created for a Composite when CreateV1Usages is true and protected composed resources exist",
+			args: args{
+				req: &fnv1.RunFunctionRequest{
+					Meta: &fnv1.RequestMeta{Tag: "hello"},
+					Input: resource.MustStructJSON(`{
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input",
+						"enableV1Mode": true
+					}`),
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"apiVersion": "test.crossplane.io/v1",
+								"kind": "TestXR",
+								"metadata": {
+									"name": "my-test-xr"
+								}
+							}`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"ready-composed-resource": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "test.crossplane.io/v1",
+									"kind": "TestComposed",
+									"metadata": {
+										"name": "my-test-composed",
+										"labels": {
+											"protection.fn.crossplane.io/block-deletion": "true"
+										}
+									},
+									"spec": {},
+									"status": {
+										"conditions": [
+											{
+												"type": "Ready",
+												"status": "True"
+											}
+										]
+									}
+								}`),
+							},
+						},
+					},
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"apiVersion": "test.crossplane.io/v1",
+								"kind": "TestXR",
+								"metadata": {
+									"name": "my-test-xr"
+								}
+							}`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"ready-composed-resource": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "test.crossplane.io/v1",
+									"kind": "TestComposed",
+									"metadata": {
+										"name": "my-test-composed"
+									},
+									"spec": {},
+									"status": {
+										"conditions": [
+											{
+												"type": "Ready",
+												"status": "True"
+											}
+										]
+									}
+								}`),
+							},
+						},
+					},
+				},
+			},
+			want: want{
+				rsp: &fnv1.RunFunctionResponse{
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"apiVersion": "test.crossplane.io/v1",
+								"kind": "TestXR",
+								"metadata": {
+									"name": "my-test-xr"
+								}
+							}`),
+						},
+						Resources: map[string]*fnv1.Resource{
+							"ready-composed-resource": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "test.crossplane.io/v1",
+									"kind": "TestComposed",
+									"metadata": {
+										"name": "my-test-composed",
+										"labels": {
+											"protection.fn.crossplane.io/block-deletion": "true"
+										}
+									},
+									"spec": {},
+									"status": {
+										"conditions": [
+											{
+												"type": "Ready",
+												"status": "True"
+											}
+										]
+									}
+								}`),
+							},
+							"ready-composed-resource-usage": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "apiextensions.crossplane.io/v1beta1",
+									"kind": "Usage",
+									"metadata": {
+										"name": "testcomposed-my-test-composed-403eb9-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestComposed",
+											"protection.fn.crossplane.io/protected-name": "my-test-composed",
+											"protection.fn.crossplane.io/reason-category": "label"
+										}
+									},
+									"spec": {
+										"of": {
+											"apiVersion": "test.crossplane.io/v1",
+											"kind": "TestComposed",
+											"resourceRef": {
+												"name": "my-test-composed"
+											}
+										},
+										"reason": "created by function-deletion-protection via label protection.fn.crossplane.io/block-deletion"
+									}
+								}`),
+							},
+							"xr-my-test-xr-usage": {
+								Resource: resource.MustStructJSON(`{
+									"apiVersion": "apiextensions.crossplane.io/v1beta1",
+									"kind": "Usage",
+									"metadata": {
+										"name": "testxr-my-test-xr-fd1fd4-fn-protection",
+										"labels": {
+											"app.kubernetes.io/managed-by": "function-deletion-protection",
+											"protection.fn.crossplane.io/composite": "my-test-xr",
+											"protection.fn.crossplane.io/protected-kind": "TestXR",
+											"protection.fn.crossplane.io/protected-name": "my-test-xr",
+											"protection.fn.crossplane.io/reason-category": "composed-resource"
+										}
+									},
+									"spec": {
+										"of": {
+											"apiVersion": "test.crossplane.io/v1",
+											"kind": "TestXR",
+											"resourceRef": {
+												"name": "my-test-xr"
+											}
+										},
+										"reason": "created by function-deletion-protection because a composed resource is protected"
+									}
+								}`),
+							},
+						},
+					},
+					Meta: &fnv1.ResponseMeta{Tag: "hello", Ttl: durationpb.New(1 * time.Minute)},
+					Results: []*fnv1.Result{
+						{
+							Severity: fnv1.Severity_SEVERITY_NORMAL,
+							Message:  "Deletion of the composite and 1 composed resource is blocked by Usages",
+							Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+						},
+					},
+					Conditions: []*fnv1.Condition{
+						{
+							Type:    ConditionTypeDeletionProtected,
+							Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+							Reason:  ConditionReasonProtected,
+							Message: proto.String("Composite is protected because a composed resource is protected. Protected composed resources: ready-composed-resource (via label protection.fn.crossplane.io/block-deletion)"),
+							Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+						},
+					},
+				},
+			},
+		},
+	}
 
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
@@ -1221,6 +2398,16 @@ func TestRunFunction(t *testing.T) {
 }
 
 func TestProtectRequiredResources(t *testing.T) {
+	operationUsageLabels := func(name, category string) map[string]any {
+		return map[string]any{
+			LabelManagedBy:      LabelManagedByValue,
+			LabelOperationUsage: "true",
+			LabelProtectedKind:  "TestResource",
+			LabelProtectedName:  name,
+			LabelReasonCategory: category,
+		}
+	}
+
 	type args struct {
 		rr map[string][]resource.Required
 	}
@@ -1265,14 +2452,15 @@ func TestProtectRequiredResources(t *testing.T) {
 			},
 			want: want{
 				dc: map[resource.Name]*resource.DesiredComposed{
-					"TestResource-test-watched-resource--required-resource-fn-protection": {
+					"testresource-test-watched-resource-7a0750-fn-protection": {
 						Resource: &composed.Unstructured{
 							Unstructured: unstructured.Unstructured{
 								Object: map[string]any{
 									"apiVersion": ProtectionGroupVersion,
 									"kind":       "ClusterUsage",
 									"metadata": map[string]any{
-										"name": "testresource-test-watched-resource-bcd955-fn-protection",
+										"labels": operationUsageLabels("test-watched-resource", ReasonCategoryWatchOperation),
+										"name":   "testresource-test-watched-resource-7a0750-fn-protection",
 									},
 									"spec": map[string]any{
 										"of": map[string]any{
@@ -1316,14 +2504,15 @@ func TestProtectRequiredResources(t *testing.T) {
 			},
 			want: want{
 				dc: map[resource.Name]*resource.DesiredComposed{
-					"TestResource-test-labeled-resource--required-resource-fn-protection": {
+					"testresource-test-labeled-resource-350432-fn-protection": {
 						Resource: &composed.Unstructured{
 							Unstructured: unstructured.Unstructured{
 								Object: map[string]any{
 									"apiVersion": ProtectionGroupVersion,
 									"kind":       "ClusterUsage",
 									"metadata": map[string]any{
-										"name": "testresource-test-labeled-resource-d0dacf-fn-protection",
+										"labels": operationUsageLabels("test-labeled-resource", ReasonCategoryOperation),
+										"name":   "testresource-test-labeled-resource-350432-fn-protection",
 									},
 									"spec": map[string]any{
 										"of": map[string]any{
@@ -1389,14 +2578,15 @@ func TestProtectRequiredResources(t *testing.T) {
 			},
 			want: want{
 				dc: map[resource.Name]*resource.DesiredComposed{
-					"TestResource-test-watched-resource-test-namespace-required-resource-fn-protection": {
+					"testresource-test-watched-resource-e8d67b-fn-protection": {
 						Resource: &composed.Unstructured{
 							Unstructured: unstructured.Unstructured{
 								Object: map[string]any{
 									"apiVersion": ProtectionGroupVersion,
 									"kind":       "Usage",
 									"metadata": map[string]any{
-										"name":      "testresource-test-watched-resource-bcd955-fn-protection",
+										"labels":    operationUsageLabels("test-watched-resource", ReasonCategoryWatchOperation),
+										"name":      "testresource-test-watched-resource-e8d67b-fn-protection",
 										"namespace": "test-namespace",
 									},
 									"spec": map[string]any{
@@ -1465,14 +2655,15 @@ func TestProtectRequiredResources(t *testing.T) {
 			},
 			want: want{
 				dc: map[resource.Name]*resource.DesiredComposed{
-					"TestResource-watched-resource-1--required-resource-fn-protection": {
+					"testresource-watched-resource-1-28cf70-fn-protection": {
 						Resource: &composed.Unstructured{
 							Unstructured: unstructured.Unstructured{
 								Object: map[string]any{
 									"apiVersion": ProtectionGroupVersion,
 									"kind":       "ClusterUsage",
 									"metadata": map[string]any{
-										"name": "testresource-watched-resource-1-915899-fn-protection",
+										"labels": operationUsageLabels("watched-resource-1", ReasonCategoryWatchOperation),
+										"name":   "testresource-watched-resource-1-28cf70-fn-protection",
 									},
 									"spec": map[string]any{
 										"of": map[string]any{
@@ -1488,14 +2679,15 @@ func TestProtectRequiredResources(t *testing.T) {
 							},
 						},
 					},
-					"TestResource-watched-resource-2--required-resource-fn-protection": {
+					"testresource-watched-resource-2-203648-fn-protection": {
 						Resource: &composed.Unstructured{
 							Unstructured: unstructured.Unstructured{
 								Object: map[string]any{
 									"apiVersion": ProtectionGroupVersion,
 									"kind":       "ClusterUsage",
 									"metadata": map[string]any{
-										"name": "testresource-watched-resource-2-47c204-fn-protection",
+										"labels": operationUsageLabels("watched-resource-2", ReasonCategoryWatchOperation),
+										"name":   "testresource-watched-resource-2-203648-fn-protection",
 									},
 									"spec": map[string]any{
 										"of": map[string]any{
@@ -1511,14 +2703,15 @@ func TestProtectRequiredResources(t *testing.T) {
 							},
 						},
 					},
-					"TestResource-labeled-resource--required-resource-fn-protection": {
+					"testresource-labeled-resource-9ce0f7-fn-protection": {
 						Resource: &composed.Unstructured{
 							Unstructured: unstructured.Unstructured{
 								Object: map[string]any{
 									"apiVersion": ProtectionGroupVersion,
 									"kind":       "ClusterUsage",
 									"metadata": map[string]any{
-										"name": "testresource-labeled-resource-b3e5fe-fn-protection",
+										"labels": operationUsageLabels("labeled-resource", ReasonCategoryOperation),
+										"name":   "testresource-labeled-resource-9ce0f7-fn-protection",
 									},
 									"spec": map[string]any{
 										"of": map[string]any{
@@ -1542,7 +2735,7 @@ func TestProtectRequiredResources(t *testing.T) {
 
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			dc, err := ProtectRequiredResources(tc.args.rr)
+			dc, _, err := ProtectRequiredResources(tc.args.rr, &Policy{}, &UsageGenerator{})
 
 			if diff := cmp.Diff(tc.want.dc, dc); diff != "" {
 				t.Errorf("%s\nProtectRequiredResources(...): -want dc, +got dc:\n%s", tc.reason, diff)
@@ -1554,3 +2747,515 @@ func TestProtectRequiredResources(t *testing.T) {
 		})
 	}
 }
+
+func TestProtectRequiredResourcesReport(t *testing.T) {
+	namespace := func(name string, labels map[string]any) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata": map[string]any{
+				"name":   name,
+				"labels": labels,
+			},
+		}}
+	}
+	rr := map[string][]resource.Required{
+		RequirementsNameWatchedResource: {{Resource: namespace("watched", nil)}},
+		"namespaces": {
+			{Resource: namespace("labeled", map[string]any{ProtectionLabelBlockDeletion: "true"})},
+			{Resource: namespace("unlabeled", nil)},
+		},
+	}
+	want := []RequiredResourceReport{
+		{
+			Requirement: "namespaces",
+			Resource:    ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "labeled"},
+			Protected:   true,
+			Reason:      ProtectionReasonOperation,
+			Usage:       &ObjectReference{APIVersion: ProtectionGroupVersion, Kind: "ClusterUsage", Name: "namespace-labeled-44fd4b-fn-protection"},
+		},
+		{
+			Requirement: "namespaces",
+			Resource:    ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "unlabeled"},
+		},
+		{
+			Requirement: RequirementsNameWatchedResource,
+			Resource:    ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "watched"},
+			Protected:   true,
+			Reason:      ProtectionReasonWatchOperation,
+			Usage:       &ObjectReference{APIVersion: ProtectionGroupVersion, Kind: "ClusterUsage", Name: "namespace-watched-03483c-fn-protection"},
+		},
+	}
+
+	_, got, err := ProtectRequiredResources(rr, &Policy{}, &UsageGenerator{})
+	if err != nil {
+		t.Fatalf("ProtectRequiredResources(...): %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ProtectRequiredResources(...): -want report, +got report:\n%s", diff)
+	}
+}
+
+func TestProtectResource(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	type args struct {
+		u   *unstructured.Unstructured
+		m   ProtectionMarkers
+		now time.Time
+	}
+	type want struct {
+		reason  string
+		protect bool
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NilResource": {
+			reason: "A nil resource should not be protected",
+			args: args{
+				m: DefaultProtectionMarkers(),
+			},
+			want: want{},
+		},
+		"DefaultLabel": {
+			reason: "The default label should protect a resource and be named in the reason",
+			args: args{
+				u: &unstructured.Unstructured{Object: map[string]any{
+					"metadata": map[string]any{
+						"labels": map[string]any{ProtectionLabelBlockDeletion: "True"},
+					},
+				}},
+				m: DefaultProtectionMarkers(),
+			},
+			want: want{reason: ProtectionReasonLabel, protect: true},
+		},
+		"DefaultLabelFalse": {
+			reason: "The default label set to false should not protect a resource",
+			args: args{
+				u: &unstructured.Unstructured{Object: map[string]any{
+					"metadata": map[string]any{
+						"labels": map[string]any{ProtectionLabelBlockDeletion: "false"},
+					},
+				}},
+				m: DefaultProtectionMarkers(),
+			},
+			want: want{},
+		},
+		"CustomLabel": {
+			reason: "A configured label key should protect a resource and be named in the reason",
+			args: args{
+				u: &unstructured.Unstructured{Object: map[string]any{
+					"metadata": map[string]any{
+						"labels": map[string]any{"company.io/protected": "yes"},
+					},
+				}},
+				m: ProtectionMarkers{
+					LabelKeys: []string{ProtectionLabelBlockDeletion, "company.io/protected"},
+					Values:    []string{"true", "yes"},
+				},
+			},
+			want: want{reason: "created by function-deletion-protection via label company.io/protected", protect: true},
+		},
+		"Annotation": {
+			reason: "A configured annotation key should protect a resource and be named in the reason",
+			args: args{
+				u: &unstructured.Unstructured{Object: map[string]any{
+					"metadata": map[string]any{
+						"annotations": map[string]any{"company.io/protected": "true"},
+					},
+				}},
+				m: ProtectionMarkers{
+					AnnotationKeys: []string{"company.io/protected"},
+					Values:         []string{"true"},
+				},
+			},
+			want: want{reason: "created by function-deletion-protection via annotation company.io/protected", protect: true},
+		},
+		"ValueNotAccepted": {
+			reason: "A label with a value that isn't accepted should not protect a resource",
+			args: args{
+				u: &unstructured.Unstructured{Object: map[string]any{
+					"metadata": map[string]any{
+						"labels": map[string]any{"company.io/protected": "true"},
+					},
+				}},
+				m: ProtectionMarkers{
+					LabelKeys: []string{"company.io/protected"},
+					Values:    []string{"enabled"},
+				},
+			},
+			want: want{},
+		},
+		"NotExpired": {
+			reason: "A resource should be protected until its protection expires",
+			args: args{
+				u: &unstructured.Unstructured{Object: map[string]any{
+					"metadata": map[string]any{
+						"labels":      map[string]any{ProtectionLabelBlockDeletion: "true"},
+						"annotations": map[string]any{ProtectionBlockDeletionUntil: "2026-12-31T00:00:00Z"},
+					},
+				}},
+				m:   DefaultProtectionMarkers(),
+				now: now,
+			},
+			want: want{reason: ProtectionReasonLabel, protect: true},
+		},
+		"Expired": {
+			reason: "A resource should not be protected after its protection expires",
+			args: args{
+				u: &unstructured.Unstructured{Object: map[string]any{
+					"metadata": map[string]any{
+						"labels":      map[string]any{ProtectionLabelBlockDeletion: "true"},
+						"annotations": map[string]any{ProtectionBlockDeletionUntil: "2026-10-01T00:00:00Z"},
+					},
+				}},
+				m:   DefaultProtectionMarkers(),
+				now: now,
+			},
+			want: want{},
+		},
+		"ExpiredDuration": {
+			reason: "A duration should expire protection relative to the resource's creation",
+			args: args{
+				u: &unstructured.Unstructured{Object: map[string]any{
+					"metadata": map[string]any{
+						"creationTimestamp": "2026-10-01T00:00:00Z",
+						"labels": map[string]any{
+							ProtectionLabelBlockDeletion: "true",
+							ProtectionBlockDeletionUntil: "168h",
+						},
+					},
+				}},
+				m:   DefaultProtectionMarkers(),
+				now: now,
+			},
+			want: want{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			reason, _, protect := ProtectResource(tc.args.m, tc.args.now, tc.args.u)
+
+			if diff := cmp.Diff(tc.want, want{reason: reason, protect: protect}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nProtectResource(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGenerateUsage(t *testing.T) {
+	bucket := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "s3.aws.upbound.io/v1beta1",
+		"kind":       "Bucket",
+		"metadata": map[string]any{
+			"name": "my-bucket",
+		},
+	}}
+	labels := map[string]any{
+		LabelManagedBy:      LabelManagedByValue,
+		LabelProtectedKind:  "Bucket",
+		LabelProtectedName:  "my-bucket",
+		LabelReasonCategory: ReasonCategoryLabel,
+	}
+
+	secret := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]any{
+			"name":      "my-secret",
+			"namespace": "crossplane-system",
+		},
+	}}
+
+	type args struct {
+		u              *unstructured.Unstructured
+		by             *unstructured.Unstructured
+		reason         string
+		replayDeletion bool
+		createV1Usages bool
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   map[string]any
+	}{
+		"V2Usage": {
+			reason: "A v2 ClusterUsage should be generated for a cluster scoped resource",
+			args: args{
+				u:      bucket,
+				reason: ProtectionReasonLabel,
+			},
+			want: map[string]any{
+				"apiVersion": "protection.crossplane.io/v1beta1",
+				"kind":       "ClusterUsage",
+				"metadata": map[string]any{
+					"name":   "bucket-my-bucket-264df3-fn-protection",
+					"labels": labels,
+				},
+				"spec": map[string]any{
+					"of": map[string]any{
+						"apiVersion":  "s3.aws.upbound.io/v1beta1",
+						"kind":        "Bucket",
+						"resourceRef": map[string]any{"name": "my-bucket"},
+					},
+					"reason": ProtectionReasonLabel,
+				},
+			},
+		},
+		"V2UsageReplayDeletion": {
+			reason: "A v2 Usage should set replayDeletion when requested",
+			args: args{
+				u:              bucket,
+				reason:         ProtectionReasonLabel,
+				replayDeletion: true,
+			},
+			want: map[string]any{
+				"apiVersion": "protection.crossplane.io/v1beta1",
+				"kind":       "ClusterUsage",
+				"metadata": map[string]any{
+					"name":   "bucket-my-bucket-264df3-fn-protection",
+					"labels": labels,
+				},
+				"spec": map[string]any{
+					"of": map[string]any{
+						"apiVersion":  "s3.aws.upbound.io/v1beta1",
+						"kind":        "Bucket",
+						"resourceRef": map[string]any{"name": "my-bucket"},
+					},
+					"reason":         ProtectionReasonLabel,
+					"replayDeletion": true,
+				},
+			},
+		},
+		"V1UsageReplayDeletion": {
+			reason: "A v1 Usage should set replayDeletion when requested",
+			args: args{
+				u:              bucket,
+				reason:         ProtectionReasonLabel,
+				replayDeletion: true,
+				createV1Usages: true,
+			},
+			want: map[string]any{
+				"apiVersion": "apiextensions.crossplane.io/v1beta1",
+				"kind":       "Usage",
+				"metadata": map[string]any{
+					"name":   "bucket-my-bucket-264df3-fn-protection",
+					"labels": labels,
+				},
+				"spec": map[string]any{
+					"of": map[string]any{
+						"apiVersion":  "s3.aws.upbound.io/v1beta1",
+						"kind":        "Bucket",
+						"resourceRef": map[string]any{"name": "my-bucket"},
+					},
+					"reason":         ProtectionReasonLabel,
+					"replayDeletion": true,
+				},
+			},
+		},
+		"V2UsageClusterScopedBy": {
+			reason: "A v2 Usage of a namespaced resource by a cluster scoped resource should be in the namespace of the resource",
+			args: args{
+				u:      secret,
+				by:     bucket,
+				reason: ProtectionReasonConnectionSecret,
+			},
+			want: map[string]any{
+				"apiVersion": "protection.crossplane.io/v1beta1",
+				"kind":       "Usage",
+				"metadata": map[string]any{
+					"name":      "secret-my-secret-bucket-my-bucket-5e67df-fn-protection",
+					"namespace": "crossplane-system",
+					"labels": map[string]any{
+						LabelManagedBy:      LabelManagedByValue,
+						LabelProtectedKind:  "Secret",
+						LabelProtectedName:  "my-secret",
+						LabelReasonCategory: ReasonCategoryConnectionSecret,
+					},
+				},
+				"spec": map[string]any{
+					"of": map[string]any{
+						"apiVersion":  "v1",
+						"kind":        "Secret",
+						"resourceRef": map[string]any{"name": "my-secret"},
+					},
+					"by": map[string]any{
+						"apiVersion":  "s3.aws.upbound.io/v1beta1",
+						"kind":        "Bucket",
+						"resourceRef": map[string]any{"name": "my-bucket"},
+					},
+					"reason": ProtectionReasonConnectionSecret,
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := GenerateUsage(tc.args.u, tc.args.by, tc.args.reason, tc.args.replayDeletion, tc.args.createV1Usages)
+
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nGenerateUsage(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRunFunctionPropagation(t *testing.T) {
+	req := func(input, xrLabel, xrTicket, vpcLabel string) *fnv1.RunFunctionRequest {
+		xr := resource.MustStructJSON(`{
+			"apiVersion": "test.crossplane.io/v1",
+			"kind": "TestXR",
+			"metadata": {
+				"name": "my-test-xr",
+				"labels": {"protection.fn.crossplane.io/block-deletion": "` + xrLabel + `"},
+				"annotations": {"protection.fn.crossplane.io/allow-deletion": "` + xrTicket + `"}
+			}
+		}`)
+		resources := map[string]*fnv1.Resource{
+			"vpc": {Resource: resource.MustStructJSON(`{
+				"apiVersion": "ec2.aws.upbound.io/v1beta1",
+				"kind": "VPC",
+				"metadata": {
+					"name": "my-vpc",
+					"labels": {"protection.fn.crossplane.io/block-deletion": "` + vpcLabel + `"}
+				}
+			}`)},
+			"subnet": {Resource: resource.MustStructJSON(`{
+				"apiVersion": "ec2.aws.upbound.io/v1beta1",
+				"kind": "Subnet",
+				"metadata": {"name": "my-subnet"}
+			}`)},
+		}
+		return &fnv1.RunFunctionRequest{
+			Input: resource.MustStructJSON(`{
+				"apiVersion": "protection.fn.crossplane.io/v1beta1",
+				"kind": "Input",
+				` + input + `
+			}`),
+			Observed: &fnv1.State{Composite: &fnv1.Resource{Resource: xr}, Resources: resources},
+			Desired:  &fnv1.State{Composite: &fnv1.Resource{Resource: xr}, Resources: resources},
+		}
+	}
+
+	cases := map[string]struct {
+		reason   string
+		input    string
+		xrLabel  string
+		xrTicket string
+		vpcLabel string
+		want     map[string]string
+		// condition is the status of the DeletionProtected condition.
+		condition fnv1.Status
+	}{
+		"Up": {
+			reason:   "A protected composed resource should protect its composite",
+			input:    `"propagation": "up"`,
+			vpcLabel: "true",
+			want: map[string]string{
+				"vpc-usage":           ProtectionReasonLabel,
+				"xr-my-test-xr-usage": ProtectionReasonCompositeChildResource,
+			},
+			condition: fnv1.Status_STATUS_CONDITION_TRUE,
+		},
+		"None": {
+			reason:   "A protected composed resource shouldn't protect its composite without propagation",
+			input:    `"propagation": "none"`,
+			xrLabel:  "false",
+			vpcLabel: "true",
+			want: map[string]string{
+				"vpc-usage": ProtectionReasonLabel,
+			},
+			condition: fnv1.Status_STATUS_CONDITION_TRUE,
+		},
+		"Down": {
+			reason:  "A protected composite should protect its composed resources",
+			input:   `"propagation": "down"`,
+			xrLabel: "true",
+			want: map[string]string{
+				"vpc-usage":           ProtectionReasonComposite,
+				"subnet-usage":        ProtectionReasonComposite,
+				"xr-my-test-xr-usage": ProtectionReasonLabel,
+			},
+			condition: fnv1.Status_STATUS_CONDITION_TRUE,
+		},
+		"DownDeletionAllowed": {
+			reason:    "A composite whose deletion is allowed shouldn't protect its composed resources",
+			input:     `"propagation": "down"`,
+			xrLabel:   "true",
+			xrTicket:  "TICKET-1",
+			want:      map[string]string{},
+			condition: fnv1.Status_STATUS_CONDITION_FALSE,
+		},
+		"DownOptOut": {
+			reason:   "A composed resource with the protection label set to false shouldn't inherit protection",
+			input:    `"propagation": "down"`,
+			xrLabel:  "true",
+			vpcLabel: "false",
+			want: map[string]string{
+				"subnet-usage":        ProtectionReasonComposite,
+				"xr-my-test-xr-usage": ProtectionReasonLabel,
+			},
+			condition: fnv1.Status_STATUS_CONDITION_TRUE,
+		},
+		"BothOptOut": {
+			reason:   "A protected composed resource that opts out shouldn't propagate protection to the composite",
+			input:    `"propagation": "both", "protectedResourceNames": ["vpc"]`,
+			xrLabel:  "false",
+			vpcLabel: "false",
+			want: map[string]string{
+				"vpc-usage": ProtectionReasonResourceName,
+			},
+			condition: fnv1.Status_STATUS_CONDITION_TRUE,
+		},
+		"NoneNotProtected": {
+			reason:    "Nothing should be protected without labels, and the condition should be False",
+			input:     `"propagation": "none"`,
+			xrLabel:   "false",
+			vpcLabel:  "false",
+			want:      map[string]string{},
+			condition: fnv1.Status_STATUS_CONDITION_FALSE,
+		},
+		"DownComposedOnly": {
+			reason:   "A protected composed resource shouldn't protect its composite when protection only propagates down",
+			input:    `"propagation": "down"`,
+			xrLabel:  "false",
+			vpcLabel: "true",
+			want: map[string]string{
+				"vpc-usage": ProtectionReasonLabel,
+			},
+			condition: fnv1.Status_STATUS_CONDITION_TRUE,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := &Function{log: logging.NewNopLogger()}
+			rsp, err := f.RunFunction(context.Background(), req(tc.input, tc.xrLabel, tc.xrTicket, tc.vpcLabel))
+			if err != nil {
+				t.Fatalf("%s\nf.RunFunction(...): %v", tc.reason, err)
+			}
+			got := map[string]string{}
+			for name, r := range rsp.GetDesired().GetResources() {
+				if reason, ok, _ := unstructured.NestedString(r.GetResource().AsMap(), "spec", "reason"); ok {
+					got[name] = reason
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			var condition fnv1.Status
+			for _, c := range rsp.GetConditions() {
+				if c.GetType() == ConditionTypeDeletionProtected {
+					condition = c.GetStatus()
+				}
+			}
+			if diff := cmp.Diff(tc.condition, condition); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want condition, +got condition:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -32,7 +32,303 @@ type Input struct {
 	// EnableV1Mode if enabled generate v1 Crossplane Usages
 	// By default v2 Usages and Cluster Usages are generated
 	// Support for v1 Usages will be removed in a future version.
+	// Equivalent to setting UsageAPI to v1, and ignored if UsageAPI is set.
 	// +optional
 	// +kubebuilder:default:=false
 	EnableV1Mode bool `json:"enableV1Mode,omitempty"`
+
+	// UsageAPI selects the API of generated Usages. v1 generates
+	// apiextensions.crossplane.io Usages, which cannot protect namespaced
+	// resources. v2 generates protection.crossplane.io Usages and
+	// ClusterUsages. auto generates v2 Usages when the request uses a
+	// Crossplane v2 feature, such as an Operation, a namespaced composite or
+	// namespaced composed resources, and v1 Usages otherwise. Defaults to v1
+	// if EnableV1Mode is true, and v2 otherwise.
+	// +optional
+	UsageAPI UsageAPI `json:"usageAPI,omitempty"`
+
+	// ReplayDeletion sets spec.replayDeletion on generated Usages. When a
+	// deletion is blocked by a Usage, Crossplane retries the deletion once the
+	// Usage is removed. Rules can override this setting.
+	// +optional
+	// +kubebuilder:default:=false
+	ReplayDeletion bool `json:"replayDeletion,omitempty"`
+
+	// LabelKeys are the labels that mark a resource as protected when set to
+	// one of the AcceptedValues. Defaults to
+	// protection.fn.crossplane.io/block-deletion. Setting LabelKeys replaces
+	// the default, so include it in the list to keep using it.
+	// +optional
+	LabelKeys []string `json:"labelKeys,omitempty"`
+
+	// AnnotationKeys are the annotations that mark a resource as protected
+	// when set to one of the AcceptedValues.
+	// +optional
+	AnnotationKeys []string `json:"annotationKeys,omitempty"`
+
+	// AcceptedValues are the label and annotation values that enable
+	// protection. Values are compared case-insensitively. Defaults to "true".
+	// +optional
+	AcceptedValues []string `json:"acceptedValues,omitempty"`
+
+	// ProtectedResourceNames protects composed resources by their composition
+	// resource name, without requiring a label. Glob patterns such as
+	// subnet-* are supported.
+	// +optional
+	ProtectedResourceNames []string `json:"protectedResourceNames,omitempty"`
+
+	// Rules select additional resources to protect. The protection labels and
+	// annotations are always evaluated as a built-in rule. A resource is
+	// protected if a protection label or annotation, or any Include rule
+	// matches it, and no Exclude rule matches it.
+	// +optional
+	Rules []Rule `json:"rules,omitempty"`
+
+	// Expressions are CEL expressions that protect a resource when they
+	// evaluate to true. The resource is available as the object variable, and
+	// the observed composite as the composite variable. For example:
+	// object.spec.forProvider.region == "us-east-1"
+	// Exclude rules take precedence over expressions.
+	// +optional
+	Expressions []Expression `json:"expressions,omitempty"`
+
+	// DetectStaleUsages requires the Usages previously created for required
+	// resources by Operations, and reports any that are no longer required,
+	// either because their target doesn't exist or is no longer protected.
+	// Stale Usages are reported as warnings and in the Operation's output. The
+	// Operation should require every resource that may be protected.
+	// +optional
+	// +kubebuilder:default:=false
+	DetectStaleUsages bool `json:"detectStaleUsages,omitempty"`
+
+	// StatusFieldPath is the field path of a summary of the protected
+	// resources in the composite's status, for example
+	// status.deletionProtection. The summary isn't written if the path is
+	// empty, or if the composite already has a value on the path that isn't an
+	// object. The field must be allowed by the XRD's schema.
+	// +optional
+	StatusFieldPath string `json:"statusFieldPath,omitempty"`
+
+	// InferDependencies creates Usages between composed resources that
+	// reference each other, so a resource can't be deleted before the
+	// resources that reference it. References are read from the *Ref, *Refs
+	// and *Selector fields of spec.forProvider and spec.initProvider.
+	// +optional
+	// +kubebuilder:default:=false
+	InferDependencies bool `json:"inferDependencies,omitempty"`
+
+	// DeletionOrder is the order composed resources are deleted in when the
+	// composite is deleted, for example [rta-*, subnet-*, rt, igw, vpc].
+	// Each entry is a glob pattern matching composition resource names or
+	// kinds. Resources are grouped by the first entry they match, and Usages
+	// block deletion of each group until the previous group has been deleted.
+	// Resources that don't match any entry aren't ordered.
+	// +optional
+	DeletionOrder []string `json:"deletionOrder,omitempty"`
+
+	// UsageNameTemplate is a Go template for the names of generated Usages,
+	// for example {{ .Step }}-{{ .Kind }}-{{ .Name }}. The template can use
+	// .Kind, .Group, .Name and .Namespace of the protected resource, and
+	// .Composite and .Step. The result is sanitized, and a hash of the
+	// resources is appended so names remain unique. Defaults to
+	// <kind>-<name>-<hash>-fn-protection.
+	// +optional
+	UsageNameTemplate string `json:"usageNameTemplate,omitempty"`
+
+	// UsageKeyTemplate is a Go template for the keys of generated Usages in
+	// the desired composed resources, with the same values and treatment as
+	// UsageNameTemplate. By default Usages are keyed by the composition
+	// resource name of the protected resource with a -usage suffix.
+	// +optional
+	UsageKeyTemplate string `json:"usageKeyTemplate,omitempty"`
+
+	// Step is the name of the pipeline step running the function, available
+	// to templates as .Step. Crossplane doesn't send the step name to
+	// functions, so it must be set to run the function more than once in a
+	// pipeline with distinct Usages.
+	// +optional
+	Step string `json:"step,omitempty"`
+
+	// UsageReasonTemplate is a Go template for the reason of generated Usages,
+	// which is shown when a deletion is rejected. The template can use the
+	// values of UsageNameTemplate, and .Reason, the reason generated by the
+	// function, .Rule, the name of the matched rule, expression, label or
+	// annotation, and .Annotation, the value of the resource's
+	// protection.fn.crossplane.io/reason annotation. By default the
+	// annotation's value is appended to the generated reason.
+	// +optional
+	UsageReasonTemplate string `json:"usageReasonTemplate,omitempty"`
+
+	// UsageLabels are added to every generated Usage. Usages are also labeled
+	// with app.kubernetes.io/managed-by and the kind, name, composite and
+	// reason of the protected resource, which take precedence.
+	// +optional
+	UsageLabels map[string]string `json:"usageLabels,omitempty"`
+
+	// UsageAnnotations are added to every generated Usage.
+	// +optional
+	UsageAnnotations map[string]string `json:"usageAnnotations,omitempty"`
+
+	// Propagation is the direction protection propagates between the
+	// composite and its composed resources. up protects the composite if any
+	// composed resource is protected. down protects every composed resource
+	// if the composite is protected. both does both, and none disables
+	// propagation. Composed resources with a protection label or annotation
+	// set to "false" opt out of propagation in either direction.
+	// +optional
+	// +kubebuilder:default:=up
+	Propagation Propagation `json:"propagation,omitempty"`
+
+	// ProtectSupportingResources protects the resources that protected
+	// composed resources depend on: the ProviderConfig of
+	// spec.providerConfigRef, the Secret of spec.writeConnectionSecretToRef,
+	// and the EnvironmentConfigs of the composite's spec.environmentConfigRefs.
+	// Their Usages block deletion until the protected resource is deleted.
+	// +optional
+	// +kubebuilder:default:=false
+	ProtectSupportingResources bool `json:"protectSupportingResources,omitempty"`
+
+	// ProviderConfigAPIVersions are the apiVersions of ProviderConfigs, keyed
+	// by the API group of the managed resources that use them, for example
+	// kubernetes.crossplane.io: kubernetes.crossplane.io/v1alpha1. By default
+	// the group of the managed resource is used, without the service for
+	// Upbound family providers, with version v1beta1.
+	// +optional
+	ProviderConfigAPIVersions map[string]string `json:"providerConfigAPIVersions,omitempty"`
+
+	// ProtectionWindows limit protection to windows of time, such as change
+	// freezes or business hours. If any windows are set, Usages that protect
+	// resources are only generated while a window is active. Usages that order
+	// deletion are always generated. The response is cached until the next
+	// window starts or ends, if that is sooner than CacheTTL.
+	// +optional
+	ProtectionWindows []ProtectionWindow `json:"protectionWindows,omitempty"`
+}
+
+// UsageAPI is the API of generated Usages.
+// +kubebuilder:validation:Enum=v1;v2;auto
+type UsageAPI string
+
+const (
+	// UsageAPIV1 generates apiextensions.crossplane.io Usages.
+	UsageAPIV1 UsageAPI = "v1"
+	// UsageAPIV2 generates protection.crossplane.io Usages and ClusterUsages.
+	UsageAPIV2 UsageAPI = "v2"
+	// UsageAPIAuto selects v1 or v2 Usages from the request.
+	UsageAPIAuto UsageAPI = "auto"
+)
+
+// Propagation is the direction protection propagates between a composite
+// and its composed resources.
+// +kubebuilder:validation:Enum=up;down;both;none
+type Propagation string
+
+const (
+	// PropagationUp protects the composite of protected composed resources.
+	PropagationUp Propagation = "up"
+	// PropagationDown protects the composed resources of a protected
+	// composite.
+	PropagationDown Propagation = "down"
+	// PropagationBoth propagates protection up and down.
+	PropagationBoth Propagation = "both"
+	// PropagationNone doesn't propagate protection.
+	PropagationNone Propagation = "none"
+)
+
+// Expression is a CEL expression that determines whether a resource is
+// protected.
+type Expression struct {
+	// Name of the expression. The name is included in the reason of any
+	// Usages created because of this expression.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Expression must evaluate to a bool. Expressions that cannot be
+	// evaluated, for example because a field doesn't exist, do not protect
+	// the resource.
+	Expression string `json:"expression"`
+}
+
+// RuleAction determines what happens to resources matched by a Rule.
+// +kubebuilder:validation:Enum=Include;Exclude
+type RuleAction string
+
+const (
+	// RuleActionInclude protects matching resources.
+	RuleActionInclude RuleAction = "Include"
+	// RuleActionExclude prevents matching resources from being protected.
+	RuleActionExclude RuleAction = "Exclude"
+)
+
+// Rule matches resources that should, or should not, be protected. All
+// fields that are set must match. A Rule with no fields set matches every
+// resource.
+type Rule struct {
+	// Name of the rule. The name is included in the reason of any Usages
+	// created because of this rule.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Action is either Include or Exclude. Defaults to Include.
+	// +optional
+	Action RuleAction `json:"action,omitempty"`
+
+	// APIVersion of matching resources. Glob patterns are supported, for
+	// example rds.aws.upbound.io/* matches all versions of the API group.
+	// +optional
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// Kind of matching resources. Glob patterns are supported.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// MatchLabels matches resources with all of the supplied labels.
+	// +optional
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+
+	// MatchExpressions matches resources using label selector requirements.
+	// +optional
+	MatchExpressions []metav1.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+
+	// ResourceNames matches composed resources by their composition resource
+	// name. Glob patterns such as subnet-* are supported. A rule with
+	// ResourceNames never matches the composite or required resources.
+	// +optional
+	ResourceNames []string `json:"resourceNames,omitempty"`
+
+	// ReplayDeletion overrides the Input's ReplayDeletion for Usages of
+	// resources matching an Include rule. The first matching rule that sets
+	// ReplayDeletion is used.
+	// +optional
+	ReplayDeletion *bool `json:"replayDeletion,omitempty"`
+}
+
+// A ProtectionWindow is a window of time during which resources are
+// protected. A window has either a Schedule and Duration, or a Start and End.
+type ProtectionWindow struct {
+	// Name of the window, reported when a window is active.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Schedule is a cron schedule of the start of the window, for example
+	// "0 9 * * 1-5" for 9am on weekdays.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// Duration of a window started by Schedule, for example 8h.
+	// +optional
+	Duration string `json:"duration,omitempty"`
+
+	// TimeZone of Schedule, for example Europe/London. Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Start of a single window, as an RFC3339 time, for example
+	// 2026-12-15T00:00:00-05:00.
+	// +optional
+	Start string `json:"start,omitempty"`
+
+	// End of a single window, as an RFC3339 time.
+	// +optional
+	End string `json:"end,omitempty"`
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+const (
+	// LabelManagedBy identifies Usages generated by this function.
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+	// LabelManagedByValue is the value of LabelManagedBy.
+	LabelManagedByValue = "function-deletion-protection"
+	// LabelProtectedKind is the kind of the resource a Usage protects.
+	LabelProtectedKind = "protection.fn.crossplane.io/protected-kind"
+	// LabelProtectedName is the name of the resource a Usage protects. It
+	// isn't set if the name isn't a valid label value.
+	LabelProtectedName = "protection.fn.crossplane.io/protected-name"
+	// LabelComposite is the name of the composite that generated a Usage. It
+	// isn't set for Operations.
+	LabelComposite = "protection.fn.crossplane.io/composite"
+	// LabelReasonCategory is the category of the reason a resource is
+	// protected, for example label or rule.
+	LabelReasonCategory = "protection.fn.crossplane.io/reason-category"
+)
+
+// Reason categories are the values of LabelReasonCategory.
+const (
+	ReasonCategoryLabel            = "label"
+	ReasonCategoryAnnotation       = "annotation"
+	ReasonCategoryResourceName     = "resource-name"
+	ReasonCategoryRule             = "rule"
+	ReasonCategoryExpression       = "expression"
+	ReasonCategoryComposedResource = "composed-resource"
+	ReasonCategoryComposite        = "composite"
+	ReasonCategoryClaim            = "claim"
+	ReasonCategoryNestedComposite  = "nested-composite"
+	ReasonCategoryProviderConfig   = "provider-config"
+	ReasonCategoryConnectionSecret = "connection-secret"
+	ReasonCategoryEnvironment      = "environment-config"
+	ReasonCategoryDependency       = "dependency"
+	ReasonCategoryDeletionOrder    = "deletion-order"
+	ReasonCategoryOperation        = "operation"
+	ReasonCategoryWatchOperation   = "watch-operation"
+)
+
+// ReasonCategory returns the category of a Usage reason, or an empty string
+// if the reason wasn't generated by this function.
+func ReasonCategory(reason string) string {
+	switch {
+	case strings.HasPrefix(reason, ProtectionReason+"via label "):
+		return ReasonCategoryLabel
+	case strings.HasPrefix(reason, ProtectionReason+"via annotation "):
+		return ReasonCategoryAnnotation
+	case reason == ProtectionReasonResourceName:
+		return ReasonCategoryResourceName
+	case strings.HasPrefix(reason, ProtectionReasonRule):
+		return ReasonCategoryRule
+	case strings.HasPrefix(reason, ProtectionReasonExpression):
+		return ReasonCategoryExpression
+	case reason == ProtectionReasonCompositeChildResource:
+		return ReasonCategoryComposedResource
+	case reason == ProtectionReasonComposite:
+		return ReasonCategoryComposite
+	case reason == ProtectionReasonClaim:
+		return ReasonCategoryClaim
+	case reason == ProtectionReasonNestedComposite:
+		return ReasonCategoryNestedComposite
+	case reason == ProtectionReasonProviderConfig:
+		return ReasonCategoryProviderConfig
+	case reason == ProtectionReasonConnectionSecret:
+		return ReasonCategoryConnectionSecret
+	case reason == ProtectionReasonEnvironmentConfig:
+		return ReasonCategoryEnvironment
+	case reason == ProtectionReasonDependency:
+		return ReasonCategoryDependency
+	case reason == ProtectionReasonDeletionOrder:
+		return ReasonCategoryDeletionOrder
+	case reason == ProtectionReasonOperation:
+		return ReasonCategoryOperation
+	case reason == ProtectionReasonWatchOperation:
+		return ReasonCategoryWatchOperation
+	}
+	return ""
+}
+
+// usageLabels returns the standard labels of a Usage of u.
+func usageLabels(u *unstructured.Unstructured, reason string) map[string]any {
+	labels := map[string]any{
+		LabelManagedBy:     LabelManagedByValue,
+		LabelProtectedKind: u.GetKind(),
+	}
+	if len(validation.IsValidLabelValue(u.GetName())) == 0 {
+		labels[LabelProtectedName] = u.GetName()
+	}
+	if c := ReasonCategory(reason); c != "" {
+		labels[LabelReasonCategory] = c
+	}
+	return labels
+}
+
+// ValidateUsageMetadata returns an error if the supplied labels or
+// annotations aren't valid.
+func ValidateUsageMetadata(labels, annotations map[string]string) error {
+	for k, v := range labels {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return errors.Errorf("invalid usage label key %q: %s", k, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+			return errors.Errorf("invalid value %q of usage label %q: %s", v, k, strings.Join(errs, ", "))
+		}
+	}
+	for k := range annotations {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return errors.Errorf("invalid usage annotation key %q: %s", k, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// addUsageMetadata adds labels and annotations to a Usage. Existing labels and
+// annotations, such as the standard labels, take precedence.
+func addUsageMetadata(usage map[string]any, labels, annotations map[string]string) {
+	u := &unstructured.Unstructured{Object: usage}
+	if len(labels) > 0 {
+		l := u.GetLabels()
+		if l == nil {
+			l = map[string]string{}
+		}
+		for k, v := range labels {
+			if _, ok := l[k]; !ok {
+				l[k] = v
+			}
+		}
+		u.SetLabels(l)
+	}
+	if len(annotations) > 0 {
+		a := u.GetAnnotations()
+		if a == nil {
+			a = map[string]string{}
+		}
+		for k, v := range annotations {
+			if _, ok := a[k]; !ok {
+				a[k] = v
+			}
+		}
+		u.SetAnnotations(a)
+	}
+}
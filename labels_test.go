@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1beta1 "github.com/stevendborrelli/function-deletion-protection/input/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestReasonCategory(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		want   string
+	}{
+		"DefaultLabel":     {reason: ProtectionReasonLabel, want: ReasonCategoryLabel},
+		"CustomLabel":      {reason: LabelReason("example.org/protect"), want: ReasonCategoryLabel},
+		"Annotation":       {reason: AnnotationReason("example.org/protect"), want: ReasonCategoryAnnotation},
+		"ResourceName":     {reason: ProtectionReasonResourceName, want: ReasonCategoryResourceName},
+		"Rule":             {reason: ruleReason("networks"), want: ReasonCategoryRule},
+		"Expression":       {reason: ProtectionReasonExpression + " prod", want: ReasonCategoryExpression},
+		"ComposedResource": {reason: ProtectionReasonCompositeChildResource, want: ReasonCategoryComposedResource},
+		"Composite":        {reason: ProtectionReasonComposite, want: ReasonCategoryComposite},
+		"Claim":            {reason: ProtectionReasonClaim, want: ReasonCategoryClaim},
+		"NestedComposite":  {reason: ProtectionReasonNestedComposite, want: ReasonCategoryNestedComposite},
+		"ProviderConfig":   {reason: ProtectionReasonProviderConfig, want: ReasonCategoryProviderConfig},
+		"ConnectionSecret": {reason: ProtectionReasonConnectionSecret, want: ReasonCategoryConnectionSecret},
+		"Environment":      {reason: ProtectionReasonEnvironmentConfig, want: ReasonCategoryEnvironment},
+		"Dependency":       {reason: ProtectionReasonDependency, want: ReasonCategoryDependency},
+		"DeletionOrder":    {reason: ProtectionReasonDeletionOrder, want: ReasonCategoryDeletionOrder},
+		"Operation":        {reason: ProtectionReasonOperation, want: ReasonCategoryOperation},
+		"WatchOperation":   {reason: ProtectionReasonWatchOperation, want: ReasonCategoryWatchOperation},
+		"Unknown":          {reason: "protected by hand", want: ""},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := ReasonCategory(tc.reason); got != tc.want {
+				t.Errorf("ReasonCategory(%q): want %q, got %q", tc.reason, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestUsageGeneratorMetadata(t *testing.T) {
+	bucket := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "s3.aws.upbound.io/v1beta1",
+		"kind":       "Bucket",
+		"metadata":   map[string]any{"name": "my-bucket"},
+	}}
+
+	type want struct {
+		labels      map[string]string
+		annotations map[string]string
+		err         bool
+	}
+	cases := map[string]struct {
+		reason    string
+		in        *v1beta1.Input
+		composite string
+		want      want
+	}{
+		"StandardLabels": {
+			reason: "Usages of an Operation should have the standard labels, without a composite",
+			in:     &v1beta1.Input{},
+			want: want{labels: map[string]string{
+				LabelManagedBy:      LabelManagedByValue,
+				LabelProtectedKind:  "Bucket",
+				LabelProtectedName:  "my-bucket",
+				LabelReasonCategory: ReasonCategoryLabel,
+			}},
+		},
+		"InputLabelsAndAnnotations": {
+			reason: "Labels and annotations from the input should be added, and the standard labels take precedence",
+			in: &v1beta1.Input{
+				UsageLabels: map[string]string{
+					"team":         "platform",
+					LabelManagedBy: "someone-else",
+				},
+				UsageAnnotations: map[string]string{"example.org/ticket": "OPS-1234"},
+			},
+			composite: "my-xr",
+			want: want{
+				labels: map[string]string{
+					"team":              "platform",
+					LabelManagedBy:      LabelManagedByValue,
+					LabelComposite:      "my-xr",
+					LabelProtectedKind:  "Bucket",
+					LabelProtectedName:  "my-bucket",
+					LabelReasonCategory: ReasonCategoryLabel,
+				},
+				annotations: map[string]string{"example.org/ticket": "OPS-1234"},
+			},
+		},
+		"InvalidLabelValue": {
+			reason: "A label from the input with an invalid value should return an error",
+			in:     &v1beta1.Input{UsageLabels: map[string]string{"team": "platform team"}},
+			want:   want{err: true},
+		},
+		"InvalidAnnotationKey": {
+			reason: "An annotation from the input with an invalid key should return an error",
+			in:     &v1beta1.Input{UsageAnnotations: map[string]string{"ticket number": "OPS-1234"}},
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			g, err := NewUsageGenerator(tc.in, v1beta1.UsageAPIV2, tc.composite)
+			if (err != nil) != tc.want.err {
+				t.Fatalf("%s\nNewUsageGenerator(...): want error %t, got %v", tc.reason, tc.want.err, err)
+			}
+			if err != nil {
+				return
+			}
+			usage, _, err := g.Generate(bucket, nil, ProtectionReasonLabel, false)
+			if err != nil {
+				t.Fatalf("%s\ng.Generate(...): %v", tc.reason, err)
+			}
+			u := &unstructured.Unstructured{Object: usage}
+			if diff := cmp.Diff(tc.want.labels, u.GetLabels()); diff != "" {
+				t.Errorf("%s\ng.Generate(...): -want labels, +got labels:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.annotations, u.GetAnnotations()); diff != "" {
+				t.Errorf("%s\ng.Generate(...): -want annotations, +got annotations:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -2,30 +2,75 @@
 package main
 
 import (
+	"net"
+	"net/http"
+	"time"
+
 	"github.com/alecthomas/kong"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/crossplane/function-sdk-go"
 )
 
+// Globals are flags shared by all commands.
+type Globals struct {
+	Debug bool `help:"Emit debug logs in addition to info logs." short:"d"`
+}
+
 // CLI of this Function.
 type CLI struct {
-	Debug bool `help:"Emit debug logs in addition to info logs." short:"d"`
+	Globals
 
+	Serve  ServeCmd  `cmd:"" default:"withargs" help:"Serve the function over gRPC. This is the default command."`
+	Render RenderCmd `cmd:""                    help:"Run the function locally and print the Usages and results it returns."`
+	Audit  AuditCmd  `cmd:""                    help:"Report which resources in a set of manifests would be protected."`
+}
+
+// ServeCmd serves the function over gRPC.
+type ServeCmd struct {
 	Network            string `default:"tcp"                                                                                        help:"Network on which to listen for gRPC connections."`
 	Address            string `default:":9443"                                                                                      help:"Address at which to listen for gRPC connections."`
 	TLSCertsDir        string `env:"TLS_SERVER_CERTS_DIR"                                                                           help:"Directory containing server certs (tls.key, tls.crt) and the CA used to verify client certificates (ca.crt)"`
 	Insecure           bool   `help:"Run without mTLS credentials. If you supply this flag --tls-server-certs-dir will be ignored."`
 	MaxRecvMessageSize int    `default:"4"                                                                                          help:"Maximum size of received messages in MB."`
+	MetricsAddress     string `help:"Address at which to serve Prometheus metrics. Metrics are not served if unset."`
 }
 
 // Run this Function.
-func (c *CLI) Run() error {
-	log, err := function.NewLogger(c.Debug)
+func (c *ServeCmd) Run(g *Globals) error {
+	log, err := function.NewLogger(g.Debug)
 	if err != nil {
 		return err
 	}
 
-	return function.Serve(&Function{log: log},
+	fn := &Function{log: log}
+	if c.MetricsAddress != "" {
+		fn.metrics = NewMetrics()
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+			fn.metrics,
+		)
+
+		// Listen before serving gRPC so that an unusable address is an error.
+		lis, err := net.Listen("tcp", c.MetricsAddress)
+		if err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+		srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := srv.Serve(lis); err != nil {
+				log.Info("Stopped serving metrics", "error", err)
+			}
+		}()
+	}
+
+	return function.Serve(fn,
 		function.Listen(c.Network, c.Address),
 		function.MTLSCertificates(c.TLSCertsDir),
 		function.Insecure(c.Insecure),
@@ -33,6 +78,7 @@ func (c *CLI) Run() error {
 }
 
 func main() {
-	ctx := kong.Parse(&CLI{}, kong.Description("A Crossplane Composition Function."))
-	ctx.FatalIfErrorf(ctx.Run())
+	cli := &CLI{}
+	ctx := kong.Parse(cli, kong.Description("A Crossplane Composition Function."))
+	ctx.FatalIfErrorf(ctx.Run(&cli.Globals))
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+const (
+	// CapabilityComposition labels requests to run the function in a
+	// Composition.
+	CapabilityComposition = "composition"
+	// CapabilityOperation labels requests to run the function in an
+	// Operation.
+	CapabilityOperation = "operation"
+
+	metricsNamespace = "function_deletion_protection"
+)
+
+// Metrics records Prometheus metrics about the function. A nil *Metrics
+// records nothing.
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	usages   *prometheus.CounterVec
+	fatals   *prometheus.CounterVec
+}
+
+// NewMetrics returns metrics that must be registered with a Prometheus
+// registry before they are served.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "run_function_requests_total",
+			Help:      "Total number of RunFunction requests, by capability.",
+		}, []string{"capability"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "run_function_duration_seconds",
+			Help:      "Latency of RunFunction requests, by capability.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"capability"}),
+		usages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "usages_generated_total",
+			Help:      "Total number of Usages generated, by reason category and by kind of the protected resource.",
+		}, []string{"reason", "kind"}),
+		fatals: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "fatal_results_total",
+			Help:      "Total number of Fatal results returned, by error path.",
+		}, []string{"path"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.duration.Describe(ch)
+	m.usages.Describe(ch)
+	m.fatals.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.duration.Collect(ch)
+	m.usages.Collect(ch)
+	m.fatals.Collect(ch)
+}
+
+// ObserveRequest records a RunFunction request that started at the supplied
+// time.
+func (m *Metrics) ObserveRequest(capability string, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(capability).Inc()
+	m.duration.WithLabelValues(capability).Observe(time.Since(start).Seconds())
+}
+
+// ObserveUsages records the supplied generated Usages.
+func (m *Metrics) ObserveUsages(usages map[resource.Name]*resource.DesiredComposed) {
+	if m == nil {
+		return
+	}
+	for _, u := range usages {
+		kind, _, _ := unstructured.NestedString(u.Resource.Object, "spec", "of", "kind")
+		m.usages.WithLabelValues(u.Resource.GetLabels()[LabelReasonCategory], kind).Inc()
+	}
+}
+
+// ObserveFatal records a Fatal result returned from the supplied error path.
+func (m *Metrics) ObserveFatal(path string) {
+	if m == nil {
+		return
+	}
+	m.fatals.WithLabelValues(path).Inc()
+}
+
+// Capability returns the capability the function is running with. Only
+// Compositions supply an observed composite.
+func Capability(req *fnv1.RunFunctionRequest) string {
+	if req.GetObserved().GetComposite() != nil {
+		return CapabilityComposition
+	}
+	return CapabilityOperation
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+func TestRunFunctionMetrics(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		req    *fnv1.RunFunctionRequest
+		want   string
+	}{
+		"Composition": {
+			reason: "A Composition request and the Usage generated for a labeled composite should be counted",
+			req: &fnv1.RunFunctionRequest{
+				Input: resource.MustStructJSON(`{
+					"apiVersion": "template.fn.crossplane.io/v1beta1",
+					"kind": "Input"
+				}`),
+				Observed: &fnv1.State{
+					Composite: &fnv1.Resource{
+						Resource: resource.MustStructJSON(`{
+							"apiVersion": "test.crossplane.io/v1",
+							"kind": "TestXR",
+							"metadata": {
+								"name": "my-test-xr",
+								"labels": {
+									"protection.fn.crossplane.io/block-deletion": "true"
+								}
+							}
+						}`),
+					},
+				},
+			},
+			want: `
+				# HELP function_deletion_protection_run_function_requests_total Total number of RunFunction requests, by capability.
+				# TYPE function_deletion_protection_run_function_requests_total counter
+				function_deletion_protection_run_function_requests_total{capability="composition"} 1
+				# HELP function_deletion_protection_usages_generated_total Total number of Usages generated, by reason category and by kind of the protected resource.
+				# TYPE function_deletion_protection_usages_generated_total counter
+				function_deletion_protection_usages_generated_total{kind="TestXR",reason="label"} 1
+			`,
+		},
+		"Operation": {
+			reason: "An Operation request and the Usage generated for a watched resource should be counted",
+			req: &fnv1.RunFunctionRequest{
+				Input: resource.MustStructJSON(`{
+					"apiVersion": "template.fn.crossplane.io/v1beta1",
+					"kind": "Input"
+				}`),
+				RequiredResources: map[string]*fnv1.Resources{
+					RequirementsNameWatchedResource: {
+						Items: []*fnv1.Resource{{
+							Resource: resource.MustStructJSON(`{
+								"apiVersion": "v1",
+								"kind": "Namespace",
+								"metadata": {"name": "prod"}
+							}`),
+						}},
+					},
+				},
+			},
+			want: `
+				# HELP function_deletion_protection_run_function_requests_total Total number of RunFunction requests, by capability.
+				# TYPE function_deletion_protection_run_function_requests_total counter
+				function_deletion_protection_run_function_requests_total{capability="operation"} 1
+				# HELP function_deletion_protection_usages_generated_total Total number of Usages generated, by reason category and by kind of the protected resource.
+				# TYPE function_deletion_protection_usages_generated_total counter
+				function_deletion_protection_usages_generated_total{kind="Namespace",reason="watch-operation"} 1
+			`,
+		},
+		"Fatal": {
+			reason: "A Fatal result should be counted by its error path",
+			req: &fnv1.RunFunctionRequest{
+				Input: resource.MustStructJSON(`{
+					"apiVersion": "template.fn.crossplane.io/v1beta1",
+					"kind": "Input",
+					"cacheTTL": "5x"
+				}`),
+			},
+			want: `
+				# HELP function_deletion_protection_fatal_results_total Total number of Fatal results returned, by error path.
+				# TYPE function_deletion_protection_fatal_results_total counter
+				function_deletion_protection_fatal_results_total{path="cache-ttl"} 1
+				# HELP function_deletion_protection_run_function_requests_total Total number of RunFunction requests, by capability.
+				# TYPE function_deletion_protection_run_function_requests_total counter
+				function_deletion_protection_run_function_requests_total{capability="operation"} 1
+			`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			m := NewMetrics()
+			f := &Function{log: logging.NewNopLogger(), metrics: m}
+			if _, err := f.RunFunction(context.Background(), tc.req); err != nil {
+				t.Fatalf("%s\nf.RunFunction(...): %v", tc.reason, err)
+			}
+			if err := testutil.CollectAndCompare(m, strings.NewReader(tc.want),
+				"function_deletion_protection_run_function_requests_total",
+				"function_deletion_protection_usages_generated_total",
+				"function_deletion_protection_fatal_results_total",
+			); err != nil {
+				t.Errorf("%s\nf.RunFunction(...): %v", tc.reason, err)
+			}
+			if got := testutil.CollectAndCount(m, "function_deletion_protection_run_function_duration_seconds"); got != 1 {
+				t.Errorf("%s\nf.RunFunction(...): want 1 latency histogram, got %d", tc.reason, got)
+			}
+		})
+	}
+}
+
+func TestCapability(t *testing.T) {
+	cases := map[string]struct {
+		req  *fnv1.RunFunctionRequest
+		want string
+	}{
+		"Composition": {
+			req:  &fnv1.RunFunctionRequest{Observed: &fnv1.State{Composite: &fnv1.Resource{}}},
+			want: CapabilityComposition,
+		},
+		"Operation": {
+			req:  &fnv1.RunFunctionRequest{},
+			want: CapabilityOperation,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := Capability(tc.req); got != tc.want {
+				t.Errorf("Capability(...): want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
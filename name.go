@@ -3,7 +3,17 @@ package main
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
+	"io"
 	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
 )
 
 const (
@@ -15,9 +25,23 @@ const (
 
 // GenerateName generates a valid Kubernetes name.
 func GenerateName(name, suffix string) string {
-	h := sha256.Sum256([]byte(name))
-	hEncoded := hex.EncodeToString(h[:])[:hashLength]
-	fullSuffix := hEncoded + "-" + suffix
+	return generateName(name, name, suffix)
+}
+
+// generateName returns a DNS-1123 name made of the sanitized name, a hash of
+// the identity, and the optional suffix. The name is truncated if necessary.
+// The identity should uniquely identify what is being named, so that names
+// that are the same after sanitizing or truncation remain unique.
+func generateName(name, identity, suffix string) string {
+	h := sha256.Sum256([]byte(identity))
+	fullSuffix := hex.EncodeToString(h[:])[:hashLength]
+	if suffix != "" {
+		fullSuffix += "-" + suffix
+	}
+	name = sanitizeName(name)
+	if name == "" {
+		return fullSuffix
+	}
 	fullName := name + "-" + fullSuffix
 
 	if len(fullName) <= maxKubernetesNameLength {
@@ -34,3 +58,132 @@ func GenerateName(name, suffix string) string {
 
 	return truncatedName + fullSuffix
 }
+
+// sanitizeName lowercases a name and replaces runs of characters that aren't
+// valid in a DNS-1123 label with a single hyphen. Leading and trailing hyphens
+// are removed.
+func sanitizeName(name string) string {
+	var b strings.Builder
+	hyphen := true
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		if !hyphen {
+			b.WriteRune('-')
+			hyphen = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// objectIdentity uniquely identifies an object by its API group, kind,
+// namespace and name. The API version is omitted so the identity doesn't
+// change when a resource is served at a new version.
+func objectIdentity(u *unstructured.Unstructured) string {
+	gv, _ := schema.ParseGroupVersion(u.GetAPIVersion())
+	return strings.Join([]string{gv.Group, u.GetKind(), u.GetNamespace(), u.GetName()}, "/")
+}
+
+// usageIdentity uniquely identifies the Usage of u, optionally by another
+// resource.
+func usageIdentity(u, by *unstructured.Unstructured) string {
+	if by == nil {
+		return objectIdentity(u)
+	}
+	return objectIdentity(u) + " " + objectIdentity(by)
+}
+
+// usageName returns the name of the Usage of u, optionally by another
+// resource. The hash includes the API group and namespace of the resources,
+// so resources with the same kind and name get different Usages.
+func usageName(u, by *unstructured.Unstructured) string {
+	name := u.GetKind() + "-" + u.GetName()
+	if by != nil {
+		name += "-" + by.GetKind() + "-" + by.GetName()
+	}
+	return generateName(name, usageIdentity(u, by), UsageNameSuffix)
+}
+
+// UsageTemplateValues are the values available to the usageNameTemplate and
+// usageKeyTemplate. Kind, Group, Name and Namespace describe the protected
+// resource.
+type UsageTemplateValues struct {
+	Kind      string
+	Group     string
+	Name      string
+	Namespace string
+	Composite string
+	Step      string
+}
+
+// For returns the values with the resource fields set to u.
+func (v UsageTemplateValues) For(u *unstructured.Unstructured) UsageTemplateValues {
+	gv, _ := schema.ParseGroupVersion(u.GetAPIVersion())
+	v.Kind, v.Group, v.Name, v.Namespace = u.GetKind(), gv.Group, u.GetName(), u.GetNamespace()
+	return v
+}
+
+// ParseUsageTemplate parses a Usage name, key or reason template. It returns
+// nil if the text is empty. The template is executed once with the supplied
+// empty values to check that it only refers to known values.
+func ParseUsageTemplate(name, text string, values any) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse %s", name)
+	}
+	if err := t.Execute(io.Discard, values); err != nil {
+		return nil, errors.Wrapf(err, "cannot execute %s", name)
+	}
+	return t, nil
+}
+
+// templateName executes a Usage name or key template for the Usage of u,
+// optionally by another resource. The result is made a valid name, and a hash
+// of the resources and the result is appended so names remain unique.
+func templateName(t *template.Template, v UsageTemplateValues, u, by *unstructured.Unstructured) (string, error) {
+	v = v.For(u)
+	b := &strings.Builder{}
+	if err := t.Execute(b, v); err != nil {
+		return "", errors.Wrapf(err, "cannot execute %s", t.Name())
+	}
+	return generateName(b.String(), usageIdentity(u, by)+" "+b.String(), ""), nil
+}
+
+// MergeDesired adds Usages to the desired composed resources. It returns an
+// error, without adding any Usages, if a key is already desired.
+func MergeDesired(desired, usages map[resource.Name]*resource.DesiredComposed) error {
+	for name := range usages {
+		if _, ok := desired[name]; ok {
+			return errors.Errorf("cannot add Usage %q: a desired composed resource with that name already exists", name)
+		}
+	}
+	for name, u := range usages {
+		desired[name] = u
+	}
+	return nil
+}
+
+// dependencyKey returns the key of the Usage that blocks deletion of the
+// composed resource of until the composed resource by is deleted. The key
+// includes a hash of both names, so names that contain hyphens can't produce
+// the same key.
+func dependencyKey(by, of resource.Name) resource.Name {
+	h := sha256.Sum256([]byte(string(by) + "\x00" + string(of)))
+	return resource.Name(fmt.Sprintf("%s-%s-%s-dependency-usage", by, of, hex.EncodeToString(h[:])[:hashLength]))
+}
+
+// addUsage adds a Usage to a batch of Usages. It returns an error, rather than
+// replacing a Usage, if another Usage in the batch has the same key.
+func addUsage(dc map[resource.Name]*resource.DesiredComposed, key resource.Name, usage *composed.Unstructured) error {
+	if _, ok := dc[key]; ok {
+		return errors.Errorf("cannot add Usage %q: another Usage has the same key", key)
+	}
+	dc[key] = &resource.DesiredComposed{Resource: usage}
+	return nil
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"maps"
+	"slices"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/testing/protocmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
 )
 
 func TestGenerateName(t *testing.T) {
@@ -80,6 +87,26 @@ func TestGenerateName(t *testing.T) {
 				generatedName: "long-resource-name-for-kubernetes-environment-tes-512922-suffix",
 			},
 		},
+		"InvalidCharacters": {
+			reason: "characters that aren't valid in a DNS-1123 label should be replaced by a hyphen",
+			args: args{
+				name:   "Bucket-my_bucket.example.com",
+				suffix: "fn-protection",
+			},
+			want: want{
+				generatedName: "bucket-my-bucket-example-com-76a081-fn-protection",
+			},
+		},
+		"OnlyInvalidCharacters": {
+			reason: "a name with no valid characters should be replaced by the hash and suffix",
+			args: args{
+				name:   "--__--",
+				suffix: "fn-protection",
+			},
+			want: want{
+				generatedName: "5be6dc-fn-protection",
+			},
+		},
 	}
 
 	for name, tc := range cases {
@@ -90,9 +117,166 @@ func TestGenerateName(t *testing.T) {
 				t.Errorf("%s\nGenerateName(...): -want rsp, +got rsp:\n%s", tc.reason, diff)
 			}
 
-			// Verify the generated name never exceeds 63 characters
-			if len(got) > 63 {
-				t.Errorf("Generated name exceeds Kubernetes limit: %d characters (max 63)", len(got))
+			// Verify the generated name is a valid DNS-1123 label, which
+			// also limits it to 63 characters.
+			if errs := validation.IsDNS1123Label(got); len(errs) > 0 {
+				t.Errorf("Generated name %q is not a valid DNS-1123 label: %v", got, errs)
+			}
+		})
+	}
+}
+
+func TestUsageName(t *testing.T) {
+	obj := func(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion(apiVersion)
+		u.SetKind(kind)
+		u.SetNamespace(namespace)
+		u.SetName(name)
+		return u
+	}
+	bucket := obj("s3.aws.upbound.io/v1beta1", "Bucket", "", "my-bucket")
+
+	cases := map[string]struct {
+		reason string
+		a      *unstructured.Unstructured
+		b      *unstructured.Unstructured
+	}{
+		"DifferentGroup": {
+			reason: "Resources with the same kind and name in different API groups should have different Usages",
+			a:      bucket,
+			b:      obj("storage.gcp.upbound.io/v1beta1", "Bucket", "", "my-bucket"),
+		},
+		"DifferentNamespace": {
+			reason: "Resources with the same kind and name in different namespaces should have different Usages",
+			a:      obj("example.org/v1", "Bucket", "dev", "my-bucket"),
+			b:      obj("example.org/v1", "Bucket", "prod", "my-bucket"),
+		},
+		"SameAfterSanitizing": {
+			reason: "Resources with names that are the same once sanitized should have different Usages",
+			a:      obj("example.org/v1", "Bucket", "", "my.bucket"),
+			b:      obj("example.org/v1", "Bucket", "", "my-bucket"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			a, b := usageName(tc.a, nil), usageName(tc.b, nil)
+			if a == b {
+				t.Errorf("%s\nusageName(...): both resources have Usage %q", tc.reason, a)
+			}
+			if by := usageName(bucket, tc.a); by == usageName(bucket, tc.b) {
+				t.Errorf("%s\nusageName(...): both using resources have Usage %q", tc.reason, by)
+			}
+		})
+	}
+}
+
+func TestMergeDesired(t *testing.T) {
+	dc := func() *resource.DesiredComposed { return &resource.DesiredComposed{Resource: composed.New()} }
+
+	type want struct {
+		desired []resource.Name
+		err     bool
+	}
+	cases := map[string]struct {
+		reason  string
+		desired map[resource.Name]*resource.DesiredComposed
+		usages  map[resource.Name]*resource.DesiredComposed
+		want    want
+	}{
+		"NoCollision": {
+			reason:  "Usages should be added to the desired composed resources",
+			desired: map[resource.Name]*resource.DesiredComposed{"vpc": dc()},
+			usages:  map[resource.Name]*resource.DesiredComposed{"vpc-usage": dc()},
+			want:    want{desired: []resource.Name{"vpc", "vpc-usage"}},
+		},
+		"Collision": {
+			reason:  "A Usage with the same key as a desired composed resource should return an error without adding any Usages",
+			desired: map[resource.Name]*resource.DesiredComposed{"vpc": dc(), "vpc-usage": dc()},
+			usages:  map[resource.Name]*resource.DesiredComposed{"subnet-usage": dc(), "vpc-usage": dc()},
+			want:    want{desired: []resource.Name{"vpc", "vpc-usage"}, err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := MergeDesired(tc.desired, tc.usages)
+			if (err != nil) != tc.want.err {
+				t.Errorf("%s\nMergeDesired(...): want error %t, got %v", tc.reason, tc.want.err, err)
+			}
+			if diff := cmp.Diff(tc.want.desired, slices.Sorted(maps.Keys(tc.desired))); diff != "" {
+				t.Errorf("%s\nMergeDesired(...): -want desired, +got desired:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDependencyKey(t *testing.T) {
+	type dependency struct {
+		by resource.Name
+		of resource.Name
+	}
+	cases := map[string]struct {
+		reason string
+		a      dependency
+		b      dependency
+	}{
+		"HyphenatedNames": {
+			reason: "Dependencies whose names join to the same string should have different keys",
+			a:      dependency{by: "subnet-a", of: "vpc"},
+			b:      dependency{by: "subnet", of: "a-vpc"},
+		},
+		"Reversed": {
+			reason: "A dependency and its reverse should have different keys",
+			a:      dependency{by: "subnet", of: "vpc"},
+			b:      dependency{by: "vpc", of: "subnet"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			a, b := dependencyKey(tc.a.by, tc.a.of), dependencyKey(tc.b.by, tc.b.of)
+			if a == b {
+				t.Errorf("%s\ndependencyKey(...): both dependencies have key %q", tc.reason, a)
+			}
+		})
+	}
+}
+
+func TestAddUsage(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		batch   map[resource.Name]*resource.DesiredComposed
+		key     resource.Name
+		wantErr bool
+	}{
+		"NewKey": {
+			reason: "A Usage with a new key should be added to the batch",
+			batch:  map[resource.Name]*resource.DesiredComposed{"vpc-usage": {Resource: composed.New()}},
+			key:    "subnet-usage",
+		},
+		"Clash": {
+			reason:  "A Usage with the same key as another Usage in the batch should return an error",
+			batch:   map[resource.Name]*resource.DesiredComposed{"vpc-usage": {Resource: composed.New()}},
+			key:     "vpc-usage",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			existing := tc.batch["vpc-usage"]
+			usage := composed.New()
+			err := addUsage(tc.batch, tc.key, usage)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("%s\naddUsage(...): want error %t, got %v", tc.reason, tc.wantErr, err)
+			}
+			if tc.batch["vpc-usage"] != existing {
+				t.Errorf("%s\naddUsage(...): replaced an existing Usage", tc.reason)
+			}
+			if !tc.wantErr && tc.batch[tc.key].Resource != usage {
+				t.Errorf("%s\naddUsage(...): Usage wasn't added", tc.reason)
 			}
 		})
 	}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"path"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// ValidateDeletionOrder returns an error if an entry of the deletion order
+// isn't a valid glob pattern.
+func ValidateDeletionOrder(order []string) error {
+	for i, p := range order {
+		if p == "" {
+			return errors.Errorf("deletion order entry %d is empty", i)
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return errors.Wrapf(err, "invalid deletion order entry %d %q", i, p)
+		}
+	}
+	return nil
+}
+
+// OrderDependencies returns the dependencies that delete the supplied composed
+// resources in the supplied order, sorted by By and then Of. Each entry of the
+// order is a glob pattern matching composition resource names or kinds.
+// Resources are grouped by the first entry they match, and are deleted before
+// the resources of the next entry that matches any resources. Resources that
+// don't match any entry aren't ordered.
+func OrderDependencies(order []string, resources map[resource.Name]*unstructured.Unstructured) []Dependency {
+	groups := make([][]resource.Name, len(order))
+	for name, u := range resources {
+		for i, p := range order {
+			if globMatch(p, string(name)) || globMatch(p, u.GetKind()) {
+				groups[i] = append(groups[i], name)
+				break
+			}
+		}
+	}
+
+	deps := []Dependency{}
+	var by []resource.Name
+	for _, of := range groups {
+		if len(of) == 0 {
+			continue
+		}
+		for _, b := range by {
+			for _, o := range of {
+				deps = append(deps, Dependency{Of: o, By: b})
+			}
+		}
+		by = of
+	}
+	sort.Slice(deps, func(i, j int) bool {
+		if deps[i].By != deps[j].By {
+			return deps[i].By < deps[j].By
+		}
+		return deps[i].Of < deps[j].Of
+	})
+	return deps
+}
+
+// RemoveCycles returns the order dependencies that can be added to the
+// inferred dependencies without creating a cycle, and those that can't. Usages
+// that form a cycle would block each other's deletion forever. Order
+// dependencies that duplicate an inferred dependency are omitted from both.
+func RemoveCycles(order, inferred []Dependency) (kept, dropped []Dependency) {
+	// before maps each resource to the resources deleted after it.
+	before := map[resource.Name][]resource.Name{}
+	seen := map[Dependency]bool{}
+	for _, d := range inferred {
+		before[d.By] = append(before[d.By], d.Of)
+		seen[d] = true
+	}
+	kept, dropped = []Dependency{}, []Dependency{}
+	for _, d := range order {
+		switch {
+		case seen[d]:
+			continue
+		case deletedBefore(before, d.Of, d.By):
+			dropped = append(dropped, d)
+		default:
+			before[d.By] = append(before[d.By], d.Of)
+			seen[d] = true
+			kept = append(kept, d)
+		}
+	}
+	return kept, dropped
+}
+
+// deletedBefore returns true if resource a must be deleted before resource b.
+func deletedBefore(before map[resource.Name][]resource.Name, a, b resource.Name) bool {
+	visited := map[resource.Name]bool{a: true}
+	next := []resource.Name{a}
+	for len(next) > 0 {
+		n := next[0]
+		next = next[1:]
+		for _, after := range before[n] {
+			if after == b {
+				return true
+			}
+			if !visited[after] {
+				visited[after] = true
+				next = append(next, after)
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+func TestValidateDeletionOrder(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		order  []string
+		err    bool
+	}{
+		"Valid": {
+			reason: "Names, kinds and glob patterns should be valid",
+			order:  []string{"rta-*", "subnet-*", "RouteTable", "igw", "vpc"},
+		},
+		"Empty": {
+			reason: "An empty entry should return an error",
+			order:  []string{"subnet-*", ""},
+			err:    true,
+		},
+		"InvalidPattern": {
+			reason: "An invalid glob pattern should return an error",
+			order:  []string{"subnet-[", "vpc"},
+			err:    true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateDeletionOrder(tc.order)
+			if (err != nil) != tc.err {
+				t.Errorf("%s\nValidateDeletionOrder(...): want error %t, got %v", tc.reason, tc.err, err)
+			}
+		})
+	}
+}
+
+func TestOrderDependencies(t *testing.T) {
+	mr := func(kind string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "ec2.aws.upbound.io/v1beta1",
+			"kind":       kind,
+			"metadata":   map[string]any{"name": "my-" + kind},
+		}}
+	}
+	network := map[resource.Name]*unstructured.Unstructured{
+		"rta-a":    mr("RouteTableAssociation"),
+		"rta-b":    mr("RouteTableAssociation"),
+		"subnet-a": mr("Subnet"),
+		"subnet-b": mr("Subnet"),
+		"rt":       mr("RouteTable"),
+		"igw":      mr("InternetGateway"),
+		"vpc":      mr("VPC"),
+	}
+
+	cases := map[string]struct {
+		reason    string
+		order     []string
+		resources map[resource.Name]*unstructured.Unstructured
+		want      []Dependency
+	}{
+		"NoOrder": {
+			reason:    "No resources should be ordered without a deletion order",
+			resources: network,
+			want:      []Dependency{},
+		},
+		"Names": {
+			reason:    "Each group of resources should be deleted before the next group",
+			order:     []string{"rta-*", "subnet-*", "rt", "igw", "vpc"},
+			resources: network,
+			want: []Dependency{
+				{Of: "vpc", By: "igw"},
+				{Of: "igw", By: "rt"},
+				{Of: "subnet-a", By: "rta-a"},
+				{Of: "subnet-b", By: "rta-a"},
+				{Of: "subnet-a", By: "rta-b"},
+				{Of: "subnet-b", By: "rta-b"},
+				{Of: "rt", By: "subnet-a"},
+				{Of: "rt", By: "subnet-b"},
+			},
+		},
+		"Kinds": {
+			reason:    "Entries should match kinds, and resources should be grouped by the first entry they match",
+			order:     []string{"Subnet", "subnet-*", "VPC"},
+			resources: network,
+			want: []Dependency{
+				{Of: "vpc", By: "subnet-a"},
+				{Of: "vpc", By: "subnet-b"},
+			},
+		},
+		"SkipEmptyGroups": {
+			reason:    "Entries that don't match any resources should be skipped, and unmatched resources shouldn't be ordered",
+			order:     []string{"nat-*", "igw", "eip", "vpc"},
+			resources: network,
+			want:      []Dependency{{Of: "vpc", By: "igw"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := OrderDependencies(tc.order, tc.resources)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nOrderDependencies(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRemoveCycles(t *testing.T) {
+	type want struct {
+		kept    []Dependency
+		dropped []Dependency
+	}
+
+	cases := map[string]struct {
+		reason   string
+		order    []Dependency
+		inferred []Dependency
+		want     want
+	}{
+		"NoInferred": {
+			reason: "Order dependencies should be kept when nothing is inferred",
+			order:  []Dependency{{Of: "vpc", By: "subnet"}},
+			want:   want{kept: []Dependency{{Of: "vpc", By: "subnet"}}, dropped: []Dependency{}},
+		},
+		"Duplicate": {
+			reason:   "An order dependency that duplicates an inferred dependency should be omitted",
+			order:    []Dependency{{Of: "vpc", By: "subnet"}},
+			inferred: []Dependency{{Of: "vpc", By: "subnet"}},
+			want:     want{kept: []Dependency{}, dropped: []Dependency{}},
+		},
+		"Reversed": {
+			reason:   "An order dependency that reverses an inferred dependency should be dropped",
+			order:    []Dependency{{Of: "subnet", By: "vpc"}},
+			inferred: []Dependency{{Of: "vpc", By: "subnet"}},
+			want:     want{kept: []Dependency{}, dropped: []Dependency{{Of: "subnet", By: "vpc"}}},
+		},
+		"Transitive": {
+			reason:   "An order dependency that creates a longer cycle should be dropped",
+			order:    []Dependency{{Of: "rt", By: "vpc"}, {Of: "igw", By: "rt"}},
+			inferred: []Dependency{{Of: "subnet", By: "rt"}, {Of: "vpc", By: "subnet"}},
+			want:     want{kept: []Dependency{{Of: "igw", By: "rt"}}, dropped: []Dependency{{Of: "rt", By: "vpc"}}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			kept, dropped := RemoveCycles(tc.order, tc.inferred)
+			if diff := cmp.Diff(tc.want, want{kept: kept, dropped: dropped}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nRemoveCycles(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRunFunctionDeletionOrder(t *testing.T) {
+	subnet := resource.MustStructJSON(`{
+		"apiVersion": "ec2.aws.upbound.io/v1beta1",
+		"kind": "Subnet",
+		"metadata": {"name": "my-subnet"},
+		"spec": {"forProvider": {"vpcIdRef": {"name": "my-vpc"}}}
+	}`)
+	rt := resource.MustStructJSON(`{
+		"apiVersion": "ec2.aws.upbound.io/v1beta1",
+		"kind": "RouteTable",
+		"metadata": {"name": "my-rt"}
+	}`)
+	vpc := resource.MustStructJSON(`{
+		"apiVersion": "ec2.aws.upbound.io/v1beta1",
+		"kind": "VPC",
+		"metadata": {"name": "my-vpc"}
+	}`)
+	xr := resource.MustStructJSON(`{
+		"apiVersion": "test.crossplane.io/v1",
+		"kind": "TestXR",
+		"metadata": {"name": "my-test-xr"}
+	}`)
+	req := func(order string) *fnv1.RunFunctionRequest {
+		resources := map[string]*fnv1.Resource{
+			"subnet": {Resource: subnet},
+			"rt":     {Resource: rt},
+			"vpc":    {Resource: vpc},
+		}
+		return &fnv1.RunFunctionRequest{
+			Input: resource.MustStructJSON(`{
+				"apiVersion": "protection.fn.crossplane.io/v1beta1",
+				"kind": "Input",
+				"inferDependencies": true,
+				"deletionOrder": ` + order + `
+			}`),
+			Observed: &fnv1.State{Composite: &fnv1.Resource{Resource: xr}, Resources: resources},
+			Desired:  &fnv1.State{Composite: &fnv1.Resource{Resource: xr}, Resources: resources},
+		}
+	}
+
+	type want struct {
+		usages   map[string]string
+		warnings []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		req    *fnv1.RunFunctionRequest
+		want   want
+	}{
+		"Ordered": {
+			reason: "Resources should be deleted in order, and inferred dependencies take precedence",
+			req:    req(`["rt", "subnet", "vpc"]`),
+			want: want{
+				usages: map[string]string{
+					"rt-subnet-bb2d5a-dependency-usage":  ProtectionReasonDeletionOrder,
+					"subnet-vpc-a590d1-dependency-usage": ProtectionReasonDependency,
+				},
+			},
+		},
+		"Reversed": {
+			reason: "An order that reverses an inferred dependency should be dropped with a warning, rather than blocking deletion forever",
+			req:    req(`["vpc", "subnet"]`),
+			want: want{
+				usages: map[string]string{
+					"subnet-vpc-a590d1-dependency-usage": ProtectionReasonDependency,
+				},
+				warnings: []string{`cannot delete "vpc" before "subnet" as set by deletionOrder: "subnet" must be deleted first because of inferred dependencies`},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := &Function{log: logging.NewNopLogger()}
+			rsp, err := f.RunFunction(context.Background(), tc.req)
+			if err != nil {
+				t.Fatalf("f.RunFunction(...): %v", err)
+			}
+			got := want{usages: map[string]string{}}
+			for name, r := range rsp.GetDesired().GetResources() {
+				if reason, ok, _ := unstructured.NestedString(r.GetResource().AsMap(), "spec", "reason"); ok {
+					got.usages[name] = reason
+				}
+			}
+			for _, r := range rsp.GetResults() {
+				if r.GetSeverity() == fnv1.Severity_SEVERITY_WARNING {
+					got.warnings = append(got.warnings, r.GetMessage())
+				}
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRunFunctionDeletionOrderReconcile(t *testing.T) {
+	subnet := resource.MustStructJSON(`{
+		"apiVersion": "ec2.aws.upbound.io/v1beta1",
+		"kind": "Subnet",
+		"metadata": {"name": "my-subnet"},
+		"spec": {"forProvider": {"vpcIdRef": {"name": "my-vpc"}}}
+	}`)
+	vpc := resource.MustStructJSON(`{
+		"apiVersion": "ec2.aws.upbound.io/v1beta1",
+		"kind": "VPC",
+		"metadata": {"name": "my-vpc"}
+	}`)
+	xr := resource.MustStructJSON(`{
+		"apiVersion": "test.crossplane.io/v1",
+		"kind": "TestXR",
+		"metadata": {"name": "my-test-xr"}
+	}`)
+	input := resource.MustStructJSON(`{
+		"apiVersion": "protection.fn.crossplane.io/v1beta1",
+		"kind": "Input",
+		"inferDependencies": true,
+		"deletionOrder": ["subnet-*", "vpc"]
+	}`)
+	desired := func() map[string]*fnv1.Resource {
+		return map[string]*fnv1.Resource{
+			"subnet-a": {Resource: subnet},
+			"vpc":      {Resource: vpc},
+		}
+	}
+
+	// usages returns the by and of resources of each desired Usage.
+	usages := func(rsp *fnv1.RunFunctionResponse) map[string][]string {
+		u := map[string][]string{}
+		for name, r := range rsp.GetDesired().GetResources() {
+			by, _, _ := unstructured.NestedString(r.GetResource().AsMap(), "spec", "by", "resourceRef", "name")
+			of, _, _ := unstructured.NestedString(r.GetResource().AsMap(), "spec", "of", "resourceRef", "name")
+			if of != "" {
+				u[name] = []string{by, of}
+			}
+		}
+		return u
+	}
+	want := map[string][]string{
+		"subnet-a-vpc-7d3824-dependency-usage": {"my-subnet", "my-vpc"},
+	}
+
+	f := &Function{log: logging.NewNopLogger()}
+	first, err := f.RunFunction(context.Background(), &fnv1.RunFunctionRequest{
+		Input:    input,
+		Observed: &fnv1.State{Composite: &fnv1.Resource{Resource: xr}, Resources: desired()},
+		Desired:  &fnv1.State{Composite: &fnv1.Resource{Resource: xr}, Resources: desired()},
+	})
+	if err != nil {
+		t.Fatalf("f.RunFunction(...): %v", err)
+	}
+	if diff := cmp.Diff(want, usages(first)); diff != "" {
+		t.Errorf("The first reconcile should order the subnet before the VPC\nf.RunFunction(...): -want, +got:\n%s", diff)
+	}
+
+	// The Usages created by the first reconcile are observed by the second.
+	second, err := f.RunFunction(context.Background(), &fnv1.RunFunctionRequest{
+		Input:    input,
+		Observed: &fnv1.State{Composite: &fnv1.Resource{Resource: xr}, Resources: first.GetDesired().GetResources()},
+		Desired:  &fnv1.State{Composite: &fnv1.Resource{Resource: xr}, Resources: desired()},
+	})
+	if err != nil {
+		t.Fatalf("f.RunFunction(...): %v", err)
+	}
+	for _, r := range second.GetResults() {
+		if r.GetSeverity() == fnv1.Severity_SEVERITY_FATAL {
+			t.Fatalf("f.RunFunction(...): unexpected fatal result: %s", r.GetMessage())
+		}
+	}
+	if diff := cmp.Diff(usages(first), usages(second)); diff != "" {
+		t.Errorf("The second reconcile shouldn't order the function's own Usages\nf.RunFunction(...): -first, +second:\n%s", diff)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+)
+
+// OperationOutput is returned as the output of an Operation.
+type OperationOutput struct {
+	// Resources reports whether each required resource was protected.
+	Resources []RequiredResourceReport `json:"resources,omitempty"`
+
+	// StaleUsages are Usages created by Operations that are no longer
+	// required.
+	StaleUsages []StaleUsage `json:"staleUsages,omitempty"`
+}
+
+// A RequiredResourceReport records whether a required resource was protected.
+type RequiredResourceReport struct {
+	// Requirement is the name of the requirement that supplied the resource.
+	Requirement string          `json:"requirement"`
+	Resource    ObjectReference `json:"resource"`
+	Protected   bool            `json:"protected"`
+
+	// Reason and Usage are set if the resource was protected.
+	Reason string           `json:"reason,omitempty"`
+	Usage  *ObjectReference `json:"usage,omitempty"`
+}
+
+// SetOutput sets the output of the response.
+func SetOutput(rsp *fnv1.RunFunctionResponse, out *OperationOutput) error {
+	m := map[string]any{}
+	if err := convertViaJSON(&m, out); err != nil {
+		return errors.Wrap(err, "cannot convert output")
+	}
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		return errors.Wrap(err, "cannot convert output to protobuf struct")
+	}
+	rsp.Output = s
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+// LabelCrossplaneComposite is the label Crossplane sets on composed resources,
+// including nested composites, to the name of the root composite.
+const LabelCrossplaneComposite = "crossplane.io/composite"
+
+// ClaimOf returns the claim of a composite, read from spec.claimRef. It
+// returns nil if the composite isn't claimed.
+func ClaimOf(xr *unstructured.Unstructured) *unstructured.Unstructured {
+	if xr == nil || xr.Object == nil {
+		return nil
+	}
+	ref, found, err := unstructured.NestedStringMap(xr.Object, "spec", "claimRef")
+	if err != nil || !found || ref["apiVersion"] == "" || ref["kind"] == "" || ref["name"] == "" {
+		return nil
+	}
+	claim := &unstructured.Unstructured{}
+	claim.SetAPIVersion(ref["apiVersion"])
+	claim.SetKind(ref["kind"])
+	claim.SetName(ref["name"])
+	claim.SetNamespace(ref["namespace"])
+	return claim
+}
+
+// ParentOf returns the composite that composes a nested composite, which is
+// its controller owner reference. It returns nil if the composite isn't
+// nested. The crossplane.io/composite label isn't used, because Crossplane
+// copies it from the root composite, so it only names the parent of a
+// composite nested one level deep.
+func ParentOf(xr *unstructured.Unstructured) *unstructured.Unstructured {
+	if xr == nil || xr.Object == nil {
+		return nil
+	}
+	for _, ref := range xr.GetOwnerReferences() {
+		if ref.Controller == nil || !*ref.Controller {
+			continue
+		}
+		parent := &unstructured.Unstructured{}
+		parent.SetAPIVersion(ref.APIVersion)
+		parent.SetKind(ref.Kind)
+		parent.SetName(ref.Name)
+		// Owners must be in the same namespace, or cluster scoped.
+		parent.SetNamespace(xr.GetNamespace())
+		return parent
+	}
+	return nil
+}
+
+// compositeOwner is the claim or parent composite of a composite.
+type compositeOwner struct {
+	u      *unstructured.Unstructured
+	reason string
+	key    string
+}
+
+// ProtectCompositeOwners creates Usages for the claim and the parent
+// composite of a protected composite. The parent is only protected if
+// protection propagates up.
+func (f *Function) ProtectCompositeOwners(observedComposite *resource.Composite, desiredComposite *resource.Composite, policy *Policy, g *UsageGenerator) (map[resource.Name]*resource.DesiredComposed, error) {
+	xr := &observedComposite.Resource.Unstructured
+	owners := []compositeOwner{}
+	if claim := ClaimOf(xr); claim != nil {
+		owners = append(owners, compositeOwner{u: claim, reason: ProtectionReasonClaim, key: "claim-" + claim.GetName()})
+	}
+	if parent := ParentOf(xr); parent != nil && policy.PropagatesUp() {
+		owners = append(owners, compositeOwner{u: parent, reason: ProtectionReasonNestedComposite, key: "parent-" + parent.GetName()})
+		// The label only names the root composite, so we can't refer to it
+		// in a Usage.
+		if root := xr.GetLabels()[LabelCrossplaneComposite]; root != "" && root != parent.GetName() {
+			f.log.Debug("not protecting root composite of unknown kind", "root", root, "parent", parent.GetName())
+		}
+	}
+
+	dc := map[resource.Name]*resource.DesiredComposed{}
+	replay := policy.ReplayDeletion("", xr, &desiredComposite.Resource.Unstructured)
+	for _, o := range owners {
+		f.log.Debug("protecting composite owner", "kind", o.u.GetKind(), "name", o.u.GetName(), "namespace", o.u.GetNamespace())
+		usage, ok, err := g.Generate(o.u, nil, o.reason, replay)
+		if err != nil {
+			return dc, err
+		}
+		if !ok {
+			continue
+		}
+		usageComposed := composed.New()
+		if err := convertViaJSON(usageComposed, usage); err != nil {
+			return dc, errors.Wrap(err, "cannot convert usage to unstructured")
+		}
+		key, err := g.Key(resource.Name(strings.ToLower(o.key+UsageResourceSuffix)), o.u, nil)
+		if err != nil {
+			return dc, err
+		}
+		if err := addUsage(dc, key, usageComposed); err != nil {
+			return dc, err
+		}
+	}
+	return dc, nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+func TestClaimOf(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		xr     *unstructured.Unstructured
+		want   *unstructured.Unstructured
+	}{
+		"NotClaimed": {
+			reason: "A composite without a claimRef has no claim",
+			xr: &unstructured.Unstructured{Object: map[string]any{
+				"spec": map[string]any{},
+			}},
+		},
+		"Claimed": {
+			reason: "The claim should be read from spec.claimRef",
+			xr: &unstructured.Unstructured{Object: map[string]any{
+				"spec": map[string]any{
+					"claimRef": map[string]any{
+						"apiVersion": "example.org/v1",
+						"kind":       "Network",
+						"name":       "my-network",
+						"namespace":  "my-namespace",
+					},
+				},
+			}},
+			want: &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "example.org/v1",
+				"kind":       "Network",
+				"metadata": map[string]any{
+					"name":      "my-network",
+					"namespace": "my-namespace",
+				},
+			}},
+		},
+		"IncompleteRef": {
+			reason: "A claimRef without a kind should be ignored",
+			xr: &unstructured.Unstructured{Object: map[string]any{
+				"spec": map[string]any{
+					"claimRef": map[string]any{"name": "my-network"},
+				},
+			}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ClaimOf(tc.xr)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nClaimOf(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestParentOf(t *testing.T) {
+	owner := func(kind, name string, controller bool) map[string]any {
+		return map[string]any{
+			"apiVersion": "example.org/v1",
+			"kind":       kind,
+			"name":       name,
+			"uid":        "uid-" + name,
+			"controller": controller,
+		}
+	}
+	xr := func(labels map[string]any, owners ...any) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"metadata": map[string]any{
+				"name":            "my-nested-xr",
+				"namespace":       "my-namespace",
+				"labels":          labels,
+				"ownerReferences": owners,
+			},
+		}}
+	}
+	parent := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "example.org/v1",
+		"kind":       "XPlatform",
+		"metadata": map[string]any{
+			"name":      "my-platform",
+			"namespace": "my-namespace",
+		},
+	}}
+
+	cases := map[string]struct {
+		reason string
+		xr     *unstructured.Unstructured
+		want   *unstructured.Unstructured
+	}{
+		"NotNested": {
+			reason: "A composite without a controller has no parent",
+			xr:     xr(nil, owner("XPlatform", "my-platform", false)),
+		},
+		"Controller": {
+			reason: "The parent should be the controller owner reference",
+			xr:     xr(nil, owner("XOther", "my-other", false), owner("XPlatform", "my-platform", true)),
+			want:   parent,
+		},
+		"Label": {
+			reason: "The parent should be the controller named by the crossplane.io/composite label of a composite nested one level deep",
+			xr:     xr(map[string]any{LabelCrossplaneComposite: "my-platform"}, owner("XPlatform", "my-platform", true)),
+			want:   parent,
+		},
+		"NestedDeeper": {
+			reason: "The parent of a composite nested more than one level deep should be its controller, not the root named by the crossplane.io/composite label",
+			xr:     xr(map[string]any{LabelCrossplaneComposite: "my-root"}, owner("XPlatform", "my-platform", true)),
+			want:   parent,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ParentOf(tc.xr)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nParentOf(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRunFunctionCompositeOwners(t *testing.T) {
+	req := func(input, xrLabel string) *fnv1.RunFunctionRequest {
+		xr := resource.MustStructJSON(`{
+			"apiVersion": "example.org/v1",
+			"kind": "XNetwork",
+			"metadata": {
+				"name": "my-network-abcde",
+				"labels": {
+					"crossplane.io/composite": "my-platform",
+					"protection.fn.crossplane.io/block-deletion": "` + xrLabel + `"
+				},
+				"ownerReferences": [{
+					"apiVersion": "example.org/v1",
+					"kind": "XPlatform",
+					"name": "my-platform",
+					"uid": "uid-my-platform",
+					"controller": true
+				}]
+			},
+			"spec": {
+				"claimRef": {
+					"apiVersion": "example.org/v1",
+					"kind": "Network",
+					"name": "my-network",
+					"namespace": "my-namespace"
+				}
+			}
+		}`)
+		return &fnv1.RunFunctionRequest{
+			Input: resource.MustStructJSON(`{
+				"apiVersion": "protection.fn.crossplane.io/v1beta1",
+				"kind": "Input"` + input + `
+			}`),
+			Observed: &fnv1.State{Composite: &fnv1.Resource{Resource: xr}},
+			Desired:  &fnv1.State{Composite: &fnv1.Resource{Resource: xr}},
+		}
+	}
+
+	cases := map[string]struct {
+		reason   string
+		input    string
+		xrLabel  string
+		want     map[string]string
+		warnings []string
+	}{
+		"NotProtected": {
+			reason:  "The claim and parent shouldn't be protected if the composite isn't",
+			xrLabel: "false",
+			want:    map[string]string{},
+		},
+		"Protected": {
+			reason:  "The claim and parent of a protected composite should be protected",
+			xrLabel: "true",
+			want: map[string]string{
+				"xr-my-network-abcde-usage": ProtectionReasonLabel,
+				"claim-my-network-usage":    ProtectionReasonClaim,
+				"parent-my-platform-usage":  ProtectionReasonNestedComposite,
+			},
+		},
+		"NoPropagation": {
+			reason:  "The parent shouldn't be protected if protection doesn't propagate up",
+			input:   `, "propagation": "none"`,
+			xrLabel: "true",
+			want: map[string]string{
+				"xr-my-network-abcde-usage": ProtectionReasonLabel,
+				"claim-my-network-usage":    ProtectionReasonClaim,
+			},
+		},
+		"V1": {
+			reason:  "The claim can't be protected by a v1 Usage, because it's namespaced, so a warning should be returned",
+			input:   `, "usageAPI": "v1"`,
+			xrLabel: "true",
+			want: map[string]string{
+				"xr-my-network-abcde-usage": ProtectionReasonLabel,
+				"parent-my-platform-usage":  ProtectionReasonNestedComposite,
+			},
+			warnings: []string{"cannot protect namespaced Network my-namespace/my-network with a v1 Usage: set usageAPI to v2 or auto"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := &Function{log: logging.NewNopLogger()}
+			rsp, err := f.RunFunction(context.Background(), req(tc.input, tc.xrLabel))
+			if err != nil {
+				t.Fatalf("%s\nf.RunFunction(...): %v", tc.reason, err)
+			}
+			got := map[string]string{}
+			for name, r := range rsp.GetDesired().GetResources() {
+				if reason, ok, _ := unstructured.NestedString(r.GetResource().AsMap(), "spec", "reason"); ok {
+					got[name] = reason
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			var warnings []string
+			for _, r := range rsp.GetResults() {
+				if r.GetSeverity() == fnv1.Severity_SEVERITY_WARNING {
+					warnings = append(warnings, r.GetMessage())
+				}
+			}
+			if diff := cmp.Diff(tc.warnings, warnings); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want warnings, +got warnings:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	v1beta1 "github.com/stevendborrelli/function-deletion-protection/input/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// A Policy decides whether a resource requires deletion protection. It is
+// built once per request from the Function's Input. The zero value only
+// evaluates the default protection label.
+type Policy struct {
+	markers     ProtectionMarkers
+	names       []string
+	include     []rule
+	exclude     []rule
+	expressions []expression
+
+	// replayDeletion is the default for spec.replayDeletion on Usages.
+	replayDeletion bool
+
+	// composite is the observed composite, passed to CEL expressions.
+	composite map[string]any
+
+	// propagation is the direction protection propagates between the
+	// composite and its composed resources.
+	propagation v1beta1.Propagation
+
+	// suspended policies don't protect any resources.
+	suspended bool
+
+	// now is the time protection expiry is evaluated at.
+	now time.Time
+	// expired records the resources whose protection has expired, and
+	// nextExpiry is the earliest expiry of a protected resource.
+	expired    map[string]time.Time
+	nextExpiry time.Time
+}
+
+// rule is a compiled v1beta1.Rule.
+type rule struct {
+	name          string
+	apiVersion    string
+	kind          string
+	selector      labels.Selector
+	resourceNames []string
+
+	replayDeletion *bool
+}
+
+// NewPolicy compiles the rules and expressions in the supplied Input. The
+// observed composite is made available to expressions, and may be nil.
+func NewPolicy(in *v1beta1.Input, oxr *unstructured.Unstructured) (*Policy, error) {
+	p := &Policy{
+		markers:        DefaultProtectionMarkers(),
+		replayDeletion: in.ReplayDeletion,
+		composite:      map[string]any{},
+		propagation:    in.Propagation,
+	}
+	switch in.Propagation {
+	case "", v1beta1.PropagationUp, v1beta1.PropagationDown, v1beta1.PropagationBoth, v1beta1.PropagationNone:
+	default:
+		return nil, errors.Errorf("unknown propagation %q", in.Propagation)
+	}
+	if len(in.LabelKeys) > 0 {
+		p.markers.LabelKeys = in.LabelKeys
+	}
+	if len(in.AnnotationKeys) > 0 {
+		p.markers.AnnotationKeys = in.AnnotationKeys
+	}
+	if len(in.AcceptedValues) > 0 {
+		p.markers.Values = in.AcceptedValues
+	}
+	for _, n := range in.ProtectedResourceNames {
+		if _, err := path.Match(n, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid protected resource name %q", n)
+		}
+	}
+	p.names = in.ProtectedResourceNames
+	if oxr != nil && oxr.Object != nil {
+		p.composite = oxr.Object
+	}
+	for i, r := range in.Rules {
+		cr, err := compileRule(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot compile rule %d %q", i, r.Name)
+		}
+		switch r.Action {
+		case v1beta1.RuleActionInclude, "":
+			p.include = append(p.include, cr)
+		case v1beta1.RuleActionExclude:
+			p.exclude = append(p.exclude, cr)
+		default:
+			return nil, errors.Errorf("rule %d %q has unknown action %q", i, r.Name, r.Action)
+		}
+	}
+	exprs, err := compileExpressions(in.Expressions)
+	if err != nil {
+		return nil, err
+	}
+	p.expressions = exprs
+	return p, nil
+}
+
+func compileRule(r v1beta1.Rule) (rule, error) {
+	patterns := append([]string{r.APIVersion, r.Kind}, r.ResourceNames...)
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return rule{}, errors.Wrapf(err, "invalid pattern %q", p)
+		}
+	}
+	s, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+		MatchLabels:      r.MatchLabels,
+		MatchExpressions: r.MatchExpressions,
+	})
+	if err != nil {
+		return rule{}, errors.Wrap(err, "invalid label selector")
+	}
+	return rule{
+		name:           r.Name,
+		apiVersion:     r.APIVersion,
+		kind:           r.Kind,
+		selector:       s,
+		resourceNames:  r.ResourceNames,
+		replayDeletion: r.ReplayDeletion,
+	}, nil
+}
+
+// matches returns true if the rule matches the resource. The name is the
+// composition resource name, and is empty for composites and required
+// resources. Objects without a kind, such as the empty composite passed to
+// Operations, never match.
+func (r rule) matches(name resource.Name, u *unstructured.Unstructured) bool {
+	if u == nil || u.Object == nil || u.GetKind() == "" {
+		return false
+	}
+	if !globMatch(r.apiVersion, u.GetAPIVersion()) || !globMatch(r.kind, u.GetKind()) {
+		return false
+	}
+	if !r.selector.Matches(labels.Set(u.GetLabels())) {
+		return false
+	}
+	if len(r.resourceNames) == 0 {
+		return true
+	}
+	if name == "" {
+		return false
+	}
+	for _, p := range r.resourceNames {
+		if globMatch(p, string(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch matches a value against a glob pattern. An empty pattern matches
+// any value.
+func globMatch(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+// Evaluate determines whether a resource requires deletion protection and
+// returns the reason for the Usage. The name is the composition resource name
+// and may be empty. Multiple objects may be supplied for the same resource,
+// for example its desired and observed state. Exclude rules take precedence
+// over the protection labels and annotations, protected resource names, Include
+// rules and expressions. Protection by a label or annotation expires at the
+// time set by the block-deletion-until annotation or label. A suspended policy
+// doesn't protect any resources.
+func (p *Policy) Evaluate(name resource.Name, objs ...*unstructured.Unstructured) (string, bool) {
+	if p.suspended || p.Excluded(name, objs...) {
+		return "", false
+	}
+	markers := p.markers
+	if len(markers.LabelKeys) == 0 && len(markers.AnnotationKeys) == 0 {
+		markers = DefaultProtectionMarkers()
+	}
+	reason, at, ok := ProtectResource(markers, p.time(), objs...)
+	if ok {
+		if !at.IsZero() && (p.nextExpiry.IsZero() || at.Before(p.nextExpiry)) {
+			p.nextExpiry = at
+		}
+		return reason, true
+	}
+	if name != "" {
+		for _, n := range p.names {
+			if ok, _ := path.Match(n, string(name)); ok {
+				return ProtectionReasonResourceName, true
+			}
+		}
+	}
+	for _, r := range p.include {
+		for _, u := range objs {
+			if r.matches(name, u) {
+				return ruleReason(r.name), true
+			}
+		}
+	}
+	for _, e := range p.expressions {
+		for _, u := range objs {
+			if e.matches(u, p.composite) {
+				return ProtectionReasonExpression + " " + e.name, true
+			}
+		}
+	}
+	// The resource's protection by a label or annotation has expired.
+	if !at.IsZero() {
+		p.expire(name, objs, at)
+	}
+	return "", false
+}
+
+// Suspend stops the policy protecting any resources, for example outside of
+// the protection windows. Deletion is still ordered while it's suspended.
+func (p *Policy) Suspend() {
+	p.suspended = true
+}
+
+// Suspended returns true if the policy doesn't protect any resources.
+func (p *Policy) Suspended() bool {
+	return p.suspended
+}
+
+// Excluded returns true if an Exclude rule matches any of the objects.
+func (p *Policy) Excluded(name resource.Name, objs ...*unstructured.Unstructured) bool {
+	for _, r := range p.exclude {
+		for _, u := range objs {
+			if r.matches(name, u) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PropagatesUp returns true if a protected composed resource protects its
+// composite. Protection propagates up by default.
+func (p *Policy) PropagatesUp() bool {
+	switch p.propagation {
+	case "", v1beta1.PropagationUp, v1beta1.PropagationBoth:
+		return true
+	}
+	return false
+}
+
+// PropagatesDown returns true if a protected composite protects its composed
+// resources.
+func (p *Policy) PropagatesDown() bool {
+	return p.propagation == v1beta1.PropagationDown || p.propagation == v1beta1.PropagationBoth
+}
+
+// OptedOut returns true if a protection label or annotation of any of the
+// objects is set to false. Resources that opt out neither inherit the
+// protection of their composite nor propagate their own protection to it.
+func (p *Policy) OptedOut(objs ...*unstructured.Unstructured) bool {
+	markers := p.markers
+	if len(markers.LabelKeys) == 0 && len(markers.AnnotationKeys) == 0 {
+		markers = DefaultProtectionMarkers()
+	}
+	for _, u := range objs {
+		if u == nil || u.Object == nil {
+			continue
+		}
+		labels, annotations := u.GetLabels(), u.GetAnnotations()
+		for _, k := range markers.LabelKeys {
+			if strings.EqualFold(labels[k], "false") {
+				return true
+			}
+		}
+		for _, k := range markers.AnnotationKeys {
+			if strings.EqualFold(annotations[k], "false") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReplayDeletion returns whether Usages of the resource should set
+// spec.replayDeletion. The first Include rule matching any of the objects that
+// sets replayDeletion takes precedence over the Input.
+func (p *Policy) ReplayDeletion(name resource.Name, objs ...*unstructured.Unstructured) bool {
+	for _, r := range p.include {
+		if r.replayDeletion == nil {
+			continue
+		}
+		for _, u := range objs {
+			if r.matches(name, u) {
+				return *r.replayDeletion
+			}
+		}
+	}
+	return p.replayDeletion
+}
+
+// ruleReason returns the Usage reason for a resource matched by a rule.
+func ruleReason(name string) string {
+	if name == "" {
+		return ProtectionReasonRule
+	}
+	return ProtectionReasonRule + " " + name
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1beta1 "github.com/stevendborrelli/function-deletion-protection/input/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+func TestPolicyEvaluate(t *testing.T) {
+	rdsInstance := &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "rds.aws.upbound.io/v1beta1",
+			"kind":       "Instance",
+			"metadata": map[string]any{
+				"name": "my-db",
+				"labels": map[string]any{
+					"env": "prod",
+				},
+			},
+		},
+	}
+	labeledBucket := &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "s3.aws.upbound.io/v1beta1",
+			"kind":       "Bucket",
+			"metadata": map[string]any{
+				"name": "my-bucket",
+				"labels": map[string]any{
+					ProtectionLabelBlockDeletion: "true",
+				},
+			},
+		},
+	}
+
+	type args struct {
+		in   *v1beta1.Input
+		name resource.Name
+		objs []*unstructured.Unstructured
+	}
+	type want struct {
+		reason  string
+		protect bool
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoRules": {
+			reason: "Without rules only the built-in label should protect a resource",
+			args: args{
+				in:   &v1beta1.Input{},
+				objs: []*unstructured.Unstructured{rdsInstance},
+			},
+			want: want{},
+		},
+		"BuiltInLabel": {
+			reason: "The built-in label should protect a resource",
+			args: args{
+				in:   &v1beta1.Input{},
+				objs: []*unstructured.Unstructured{labeledBucket},
+			},
+			want: want{reason: ProtectionReasonLabel, protect: true},
+		},
+		"ConfiguredLabelKeysReplaceDefault": {
+			reason: "Configured label keys should replace the default label",
+			args: args{
+				in:   &v1beta1.Input{LabelKeys: []string{"company.io/protected"}},
+				objs: []*unstructured.Unstructured{labeledBucket},
+			},
+			want: want{},
+		},
+		"ConfiguredAnnotation": {
+			reason: "A configured annotation should protect a resource in addition to the default label",
+			args: args{
+				in: &v1beta1.Input{AnnotationKeys: []string{"company.io/protected"}, AcceptedValues: []string{"yes"}},
+				objs: []*unstructured.Unstructured{{Object: map[string]any{
+					"apiVersion": "s3.aws.upbound.io/v1beta1",
+					"kind":       "Bucket",
+					"metadata": map[string]any{
+						"annotations": map[string]any{"company.io/protected": "YES"},
+					},
+				}}},
+			},
+			want: want{reason: AnnotationReason("company.io/protected"), protect: true},
+		},
+		"MatchAPIVersionAndKind": {
+			reason: "A rule matching the apiVersion and kind should protect a resource",
+			args: args{
+				in: &v1beta1.Input{Rules: []v1beta1.Rule{
+					{Name: "rds-instances", APIVersion: "rds.aws.upbound.io/*", Kind: "Instance"},
+				}},
+				objs: []*unstructured.Unstructured{rdsInstance},
+			},
+			want: want{reason: ProtectionReasonRule + " rds-instances", protect: true},
+		},
+		"KindMismatch": {
+			reason: "A rule with a different kind should not protect a resource",
+			args: args{
+				in: &v1beta1.Input{Rules: []v1beta1.Rule{
+					{Kind: "Cluster"},
+				}},
+				objs: []*unstructured.Unstructured{rdsInstance},
+			},
+			want: want{},
+		},
+		"MatchLabels": {
+			reason: "A rule with matching labels should protect a resource",
+			args: args{
+				in: &v1beta1.Input{Rules: []v1beta1.Rule{
+					{MatchLabels: map[string]string{"env": "prod"}},
+				}},
+				objs: []*unstructured.Unstructured{rdsInstance},
+			},
+			want: want{reason: ProtectionReasonRule, protect: true},
+		},
+		"MatchExpressions": {
+			reason: "A rule with matching label expressions should protect a resource",
+			args: args{
+				in: &v1beta1.Input{Rules: []v1beta1.Rule{
+					{MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "env", Operator: metav1.LabelSelectorOpIn, Values: []string{"prod", "staging"}},
+					}},
+				}},
+				objs: []*unstructured.Unstructured{rdsInstance},
+			},
+			want: want{reason: ProtectionReasonRule, protect: true},
+		},
+		"ResourceNameGlob": {
+			reason: "A rule should match composition resource names using globs",
+			args: args{
+				in: &v1beta1.Input{Rules: []v1beta1.Rule{
+					{Name: "databases", ResourceNames: []string{"db-*"}},
+				}},
+				name: "db-primary",
+				objs: []*unstructured.Unstructured{rdsInstance},
+			},
+			want: want{reason: ProtectionReasonRule + " databases", protect: true},
+		},
+		"ResourceNameWithoutName": {
+			reason: "A rule with resource names should not match resources without a composition resource name",
+			args: args{
+				in: &v1beta1.Input{Rules: []v1beta1.Rule{
+					{ResourceNames: []string{"*"}},
+				}},
+				objs: []*unstructured.Unstructured{rdsInstance},
+			},
+			want: want{},
+		},
+		"ProtectedResourceName": {
+			reason: "A protected resource name should match composition resource names using globs",
+			args: args{
+				in:   &v1beta1.Input{ProtectedResourceNames: []string{"vpc", "db-*"}},
+				name: "db-primary",
+				objs: []*unstructured.Unstructured{rdsInstance},
+			},
+			want: want{reason: ProtectionReasonResourceName, protect: true},
+		},
+		"ProtectedResourceNameWithoutName": {
+			reason: "A protected resource name should not match resources without a composition resource name",
+			args: args{
+				in:   &v1beta1.Input{ProtectedResourceNames: []string{"*"}},
+				objs: []*unstructured.Unstructured{rdsInstance},
+			},
+			want: want{},
+		},
+		"ExcludeOverridesProtectedResourceName": {
+			reason: "An Exclude rule should take precedence over protected resource names",
+			args: args{
+				in: &v1beta1.Input{
+					ProtectedResourceNames: []string{"db-*"},
+					Rules:                  []v1beta1.Rule{{Action: v1beta1.RuleActionExclude, ResourceNames: []string{"db-primary"}}},
+				},
+				name: "db-primary",
+				objs: []*unstructured.Unstructured{rdsInstance},
+			},
+			want: want{},
+		},
+		"ExcludeOverridesLabel": {
+			reason: "An Exclude rule should take precedence over the built-in label",
+			args: args{
+				in: &v1beta1.Input{Rules: []v1beta1.Rule{
+					{Action: v1beta1.RuleActionExclude, Kind: "Bucket"},
+				}},
+				objs: []*unstructured.Unstructured{labeledBucket},
+			},
+			want: want{},
+		},
+		"ExcludeOverridesInclude": {
+			reason: "An Exclude rule should take precedence over Include rules",
+			args: args{
+				in: &v1beta1.Input{Rules: []v1beta1.Rule{
+					{Kind: "Instance"},
+					{Action: v1beta1.RuleActionExclude, MatchLabels: map[string]string{"env": "prod"}},
+				}},
+				objs: []*unstructured.Unstructured{rdsInstance},
+			},
+			want: want{},
+		},
+		"AnyObjectMatches": {
+			reason: "The resource should be protected if any of its objects match",
+			args: args{
+				in:   &v1beta1.Input{},
+				objs: []*unstructured.Unstructured{rdsInstance, labeledBucket},
+			},
+			want: want{reason: ProtectionReasonLabel, protect: true},
+		},
+		"EmptyObject": {
+			reason: "A rule should never match an object without a kind",
+			args: args{
+				in: &v1beta1.Input{Rules: []v1beta1.Rule{
+					{Name: "everything"},
+				}},
+				objs: []*unstructured.Unstructured{{Object: map[string]any{}}},
+			},
+			want: want{},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := NewPolicy(tc.args.in, nil)
+			if err != nil {
+				t.Fatalf("NewPolicy(...): %v", err)
+			}
+			reason, protect := p.Evaluate(tc.args.name, tc.args.objs...)
+
+			if diff := cmp.Diff(tc.want, want{reason: reason, protect: protect}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\np.Evaluate(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPolicyReplayDeletion(t *testing.T) {
+	bucket := &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "s3.aws.upbound.io/v1beta1",
+			"kind":       "Bucket",
+			"metadata": map[string]any{
+				"name": "my-bucket",
+			},
+		},
+	}
+	enabled, disabled := true, false
+
+	cases := map[string]struct {
+		reason string
+		in     *v1beta1.Input
+		want   bool
+	}{
+		"Default": {
+			reason: "replayDeletion should be disabled by default",
+			in:     &v1beta1.Input{},
+			want:   false,
+		},
+		"Input": {
+			reason: "replayDeletion should be set by the Input",
+			in:     &v1beta1.Input{ReplayDeletion: true},
+			want:   true,
+		},
+		"RuleOverridesInput": {
+			reason: "A matching rule should override the Input",
+			in: &v1beta1.Input{
+				ReplayDeletion: true,
+				Rules:          []v1beta1.Rule{{Kind: "Bucket", ReplayDeletion: &disabled}},
+			},
+			want: false,
+		},
+		"FirstRuleWins": {
+			reason: "The first matching rule that sets replayDeletion should be used",
+			in: &v1beta1.Input{Rules: []v1beta1.Rule{
+				{Kind: "Bucket"},
+				{APIVersion: "s3.aws.upbound.io/*", ReplayDeletion: &enabled},
+				{Kind: "Bucket", ReplayDeletion: &disabled},
+			}},
+			want: true,
+		},
+		"RuleDoesNotMatch": {
+			reason: "A rule that doesn't match should not override the Input",
+			in: &v1beta1.Input{
+				Rules: []v1beta1.Rule{{Kind: "Instance", ReplayDeletion: &enabled}},
+			},
+			want: false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := NewPolicy(tc.in, nil)
+			if err != nil {
+				t.Fatalf("NewPolicy(...): %v", err)
+			}
+			if got := p.ReplayDeletion("", bucket); got != tc.want {
+				t.Errorf("%s\np.ReplayDeletion(...): want %t, got %t", tc.reason, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		in      *v1beta1.Input
+		wantErr bool
+	}{
+		"ValidRules": {
+			reason: "Valid rules should compile",
+			in: &v1beta1.Input{Rules: []v1beta1.Rule{
+				{Action: v1beta1.RuleActionInclude, Kind: "Instance"},
+				{Action: v1beta1.RuleActionExclude, ResourceNames: []string{"subnet-*"}},
+			}},
+		},
+		"InvalidPattern": {
+			reason:  "An invalid glob pattern should return an error",
+			in:      &v1beta1.Input{Rules: []v1beta1.Rule{{Kind: "[Instance"}}},
+			wantErr: true,
+		},
+		"InvalidLabelSelector": {
+			reason: "An invalid label selector should return an error",
+			in: &v1beta1.Input{Rules: []v1beta1.Rule{
+				{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "Near"}}},
+			}},
+			wantErr: true,
+		},
+		"InvalidProtectedResourceName": {
+			reason:  "An invalid protected resource name pattern should return an error",
+			in:      &v1beta1.Input{ProtectedResourceNames: []string{"subnet-["}},
+			wantErr: true,
+		},
+		"UnknownAction": {
+			reason:  "An unknown action should return an error",
+			in:      &v1beta1.Input{Rules: []v1beta1.Rule{{Action: "Maybe"}}},
+			wantErr: true,
+		},
+		"UnknownPropagation": {
+			reason:  "An unknown propagation should return an error",
+			in:      &v1beta1.Input{Propagation: "sideways"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewPolicy(tc.in, nil)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("%s\nNewPolicy(...): want error %t, got %v", tc.reason, tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPolicyPropagation(t *testing.T) {
+	type want struct {
+		up   bool
+		down bool
+	}
+	cases := map[string]struct {
+		propagation v1beta1.Propagation
+		want        want
+	}{
+		"Default": {want: want{up: true}},
+		"Up":      {propagation: v1beta1.PropagationUp, want: want{up: true}},
+		"Down":    {propagation: v1beta1.PropagationDown, want: want{down: true}},
+		"Both":    {propagation: v1beta1.PropagationBoth, want: want{up: true, down: true}},
+		"None":    {propagation: v1beta1.PropagationNone, want: want{}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := NewPolicy(&v1beta1.Input{Propagation: tc.propagation}, nil)
+			if err != nil {
+				t.Fatalf("NewPolicy(...): %v", err)
+			}
+			got := want{up: p.PropagatesUp(), down: p.PropagatesDown()}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("Propagation %q: -want, +got:\n%s", tc.propagation, diff)
+			}
+		})
+	}
+}
+
+func TestPolicyOptedOut(t *testing.T) {
+	withMetadata := func(labels, annotations map[string]any) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"metadata": map[string]any{"labels": labels, "annotations": annotations},
+		}}
+	}
+
+	cases := map[string]struct {
+		reason string
+		in     *v1beta1.Input
+		u      *unstructured.Unstructured
+		want   bool
+	}{
+		"NoLabel": {
+			reason: "A resource without a protection label shouldn't opt out",
+			in:     &v1beta1.Input{},
+			u:      withMetadata(nil, nil),
+		},
+		"True": {
+			reason: "A protected resource shouldn't opt out",
+			in:     &v1beta1.Input{},
+			u:      withMetadata(map[string]any{ProtectionLabelBlockDeletion: "true"}, nil),
+		},
+		"False": {
+			reason: "A protection label set to false should opt out",
+			in:     &v1beta1.Input{},
+			u:      withMetadata(map[string]any{ProtectionLabelBlockDeletion: "False"}, nil),
+			want:   true,
+		},
+		"Annotation": {
+			reason: "A configured protection annotation set to false should opt out",
+			in:     &v1beta1.Input{AnnotationKeys: []string{"company.io/protected"}},
+			u:      withMetadata(nil, map[string]any{"company.io/protected": "false"}),
+			want:   true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := NewPolicy(tc.in, nil)
+			if err != nil {
+				t.Fatalf("NewPolicy(...): %v", err)
+			}
+			if got := p.OptedOut(tc.u); got != tc.want {
+				t.Errorf("%s\nOptedOut(...): want %t, got %t", tc.reason, tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// UsageReasonValues are the values available to the usageReasonTemplate.
+type UsageReasonValues struct {
+	UsageTemplateValues
+
+	// Reason is the reason generated by the function.
+	Reason string
+	// Rule is the name of the rule, expression, label or annotation that
+	// protects the resource, if any.
+	Rule string
+	// Annotation is the value of the resource's reason annotation.
+	Annotation string
+}
+
+// matchedName returns the name of the label, annotation, rule or expression
+// in a reason generated by the function.
+func matchedName(reason string) string {
+	for _, prefix := range []string{
+		ProtectionReason + "via label ",
+		ProtectionReason + "via annotation ",
+		ProtectionReasonRule + " ",
+		ProtectionReasonExpression + " ",
+	} {
+		if name, ok := strings.CutPrefix(reason, prefix); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// Reason returns the reason of the Usage of u. Without a reason template the
+// value of the resource's reason annotation, if any, is appended to the
+// supplied reason.
+func (g *UsageGenerator) Reason(u *unstructured.Unstructured, reason string) (string, error) {
+	annotation := strings.TrimSpace(u.GetAnnotations()[ProtectionAnnotationReason])
+	if g.ReasonTemplate == nil {
+		if annotation == "" {
+			return reason, nil
+		}
+		return reason + ": " + annotation, nil
+	}
+	v := UsageReasonValues{
+		UsageTemplateValues: g.Values.For(u),
+		Reason:              reason,
+		Rule:                matchedName(reason),
+		Annotation:          annotation,
+	}
+	b := &strings.Builder{}
+	if err := g.ReasonTemplate.Execute(b, v); err != nil {
+		return "", errors.Wrapf(err, "cannot execute %s", g.ReasonTemplate.Name())
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1beta1 "github.com/stevendborrelli/function-deletion-protection/input/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestUsageGeneratorReason(t *testing.T) {
+	bucket := func(annotations map[string]string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "s3.aws.upbound.io/v1beta1",
+			"kind":       "Bucket",
+			"metadata":   map[string]any{"name": "my-bucket"},
+		}}
+		u.SetAnnotations(annotations)
+		return u
+	}
+	owned := map[string]string{ProtectionAnnotationReason: "owned by team-data, see OPS-1234"}
+
+	type args struct {
+		in     *v1beta1.Input
+		u      *unstructured.Unstructured
+		reason string
+	}
+	type want struct {
+		reason string
+		err    bool
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Default": {
+			reason: "The generated reason should be used by default",
+			args:   args{in: &v1beta1.Input{}, u: bucket(nil), reason: ProtectionReasonLabel},
+			want:   want{reason: ProtectionReasonLabel},
+		},
+		"Annotation": {
+			reason: "The resource's reason annotation should be appended to the generated reason",
+			args:   args{in: &v1beta1.Input{}, u: bucket(owned), reason: ProtectionReasonLabel},
+			want:   want{reason: ProtectionReasonLabel + ": owned by team-data, see OPS-1234"},
+		},
+		"Template": {
+			reason: "A reason template should be executed with the resource, rule and annotation",
+			args: args{
+				in: &v1beta1.Input{
+					UsageReasonTemplate: "{{ .Kind }} {{ .Name }} of {{ .Composite }} is protected by rule {{ .Rule }}{{ with .Annotation }} ({{ . }}){{ end }}",
+				},
+				u:      bucket(owned),
+				reason: ruleReason("buckets"),
+			},
+			want: want{reason: "Bucket my-bucket of my-xr is protected by rule buckets (owned by team-data, see OPS-1234)"},
+		},
+		"TemplateGeneratedReason": {
+			reason: "A reason template should be able to refer to the generated reason",
+			args: args{
+				in:     &v1beta1.Input{UsageReasonTemplate: "{{ .Reason }}. Ask #platform before deleting."},
+				u:      bucket(nil),
+				reason: ProtectionReasonCompositeChildResource,
+			},
+			want: want{reason: ProtectionReasonCompositeChildResource + ". Ask #platform before deleting."},
+		},
+		"UnknownValue": {
+			reason: "A reason template that refers to an unknown value should return an error",
+			args:   args{in: &v1beta1.Input{UsageReasonTemplate: "{{ .Ticket }}"}},
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			g, err := NewUsageGenerator(tc.args.in, v1beta1.UsageAPIV2, "my-xr")
+			if (err != nil) != tc.want.err {
+				t.Fatalf("%s\nNewUsageGenerator(...): want error %t, got %v", tc.reason, tc.want.err, err)
+			}
+			if err != nil {
+				return
+			}
+			usage, _, err := g.Generate(tc.args.u, nil, tc.args.reason, false)
+			if err != nil {
+				t.Fatalf("%s\ng.Generate(...): %v", tc.reason, err)
+			}
+			got, _, _ := unstructured.NestedString(usage, "spec", "reason")
+			if diff := cmp.Diff(tc.want.reason, got); diff != "" {
+				t.Errorf("%s\ng.Generate(...): -want reason, +got reason:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(ReasonCategory(tc.args.reason), (&unstructured.Unstructured{Object: usage}).GetLabels()[LabelReasonCategory]); diff != "" {
+				t.Errorf("%s\ng.Generate(...): -want reason category, +got reason category:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
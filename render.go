@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	kyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crossplane/function-sdk-go"
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+const (
+	// AnnotationCompositionResourceName is the annotation Crossplane uses to
+	// record the composition resource name of a composed resource.
+	AnnotationCompositionResourceName = "crossplane.io/composition-resource-name"
+
+	// RenderResultAPIVersion is the apiVersion of results printed by the
+	// render command. It matches crossplane render.
+	RenderResultAPIVersion = "render.crossplane.io/v1beta1"
+)
+
+// RenderCmd runs the function locally against a RunFunctionRequest, or a
+// composite resource and its observed composed resources.
+type RenderCmd struct {
+	CompositeResource string `arg:""              help:"A YAML file containing the composite resource (XR)."                                    optional:"" type:"existingfile"`
+	Request           string `help:"A YAML or JSON file containing a RunFunctionRequest. Replaces the composite resource." short:"r"  type:"existingfile"`
+	ObservedResources string `help:"A YAML file or directory of YAML files containing the observed composed resources."    short:"o"  type:"path"`
+	FunctionInput     string `help:"A YAML file containing the function's Input. Overrides the Input of a request."        short:"i"  type:"existingfile"`
+}
+
+// Validate that exactly one of a request or a composite resource was supplied.
+func (c *RenderCmd) Validate() error {
+	if (c.Request == "") == (c.CompositeResource == "") {
+		return errors.New("specify either a composite resource or --request")
+	}
+	if c.Request != "" && c.ObservedResources != "" {
+		return errors.New("--observed-resources cannot be used with --request")
+	}
+	return nil
+}
+
+// Run the function locally and print the Usages and results it returns.
+func (c *RenderCmd) Run(g *Globals) error {
+	log, err := function.NewLogger(g.Debug)
+	if err != nil {
+		return err
+	}
+
+	req, err := c.request()
+	if err != nil {
+		return err
+	}
+
+	rsp, err := (&Function{log: log}).RunFunction(context.Background(), req)
+	if err != nil {
+		return errors.Wrap(err, "cannot run function")
+	}
+	return WriteRenderOutput(os.Stdout, rsp)
+}
+
+// request builds the RunFunctionRequest to render.
+func (c *RenderCmd) request() (*fnv1.RunFunctionRequest, error) {
+	var in *unstructured.Unstructured
+	if c.FunctionInput != "" {
+		objs, err := ReadObjects(c.FunctionInput)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read function input")
+		}
+		if len(objs) != 1 {
+			return nil, errors.Errorf("function input %s must contain exactly one object, found %d", c.FunctionInput, len(objs))
+		}
+		in = objs[0]
+	}
+
+	if c.Request != "" {
+		req, err := ReadRunFunctionRequest(c.Request)
+		if err != nil {
+			return nil, err
+		}
+		if in != nil {
+			s, err := resource.AsStruct(in)
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot convert function input")
+			}
+			req.Input = s
+		}
+		return req, nil
+	}
+
+	xrs, err := ReadObjects(c.CompositeResource)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read composite resource")
+	}
+	if len(xrs) != 1 {
+		return nil, errors.Errorf("composite resource %s must contain exactly one object, found %d", c.CompositeResource, len(xrs))
+	}
+	var observed []*unstructured.Unstructured
+	if c.ObservedResources != "" {
+		observed, err = ReadObjects(c.ObservedResources)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read observed resources")
+		}
+	}
+	return NewRenderRequest(xrs[0], observed, in)
+}
+
+// ReadRunFunctionRequest reads a RunFunctionRequest from a YAML or JSON file.
+func ReadRunFunctionRequest(path string) (*fnv1.RunFunctionRequest, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read request %s", path)
+	}
+	j, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse request %s", path)
+	}
+	req := &fnv1.RunFunctionRequest{}
+	if err := protojson.Unmarshal(j, req); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse request %s", path)
+	}
+	return req, nil
+}
+
+// ReadObjects reads the Kubernetes objects in a YAML or JSON file, or in the
+// .yaml, .yml and .json files of a directory. A file may contain multiple
+// YAML documents, and lists are expanded into their items.
+func ReadObjects(path string) ([]*unstructured.Unstructured, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot stat %s", path)
+	}
+	files := []string{path}
+	if fi.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read directory %s", path)
+		}
+		files = nil
+		for _, e := range entries {
+			switch filepath.Ext(e.Name()) {
+			case ".yaml", ".yml", ".json":
+				if !e.IsDir() {
+					files = append(files, filepath.Join(path, e.Name()))
+				}
+			}
+		}
+	}
+
+	objs := []*unstructured.Unstructured{}
+	for _, f := range files {
+		b, err := os.ReadFile(filepath.Clean(f))
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read %s", f)
+		}
+		d := kyaml.NewYAMLOrJSONDecoder(bytes.NewReader(b), 4096)
+		for {
+			u := &unstructured.Unstructured{}
+			if err := d.Decode(&u.Object); err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return nil, errors.Wrapf(err, "cannot parse %s", f)
+			}
+			if len(u.Object) == 0 {
+				continue
+			}
+			// Expand lists, such as the output of kubectl get -o yaml.
+			if u.IsList() {
+				if err := u.EachListItem(func(o runtime.Object) error {
+					if item, ok := o.(*unstructured.Unstructured); ok {
+						objs = append(objs, item)
+					}
+					return nil
+				}); err != nil {
+					return nil, errors.Wrapf(err, "cannot parse list in %s", f)
+				}
+				continue
+			}
+			objs = append(objs, u)
+		}
+	}
+	return objs, nil
+}
+
+// NewRenderRequest returns a RunFunctionRequest for a composite resource and
+// its observed composed resources. Observed resources are keyed by their
+// composition resource name annotation, and are also desired as if an earlier
+// function in the pipeline composed them. The Input defaults to an empty
+// Input if it is nil.
+func NewRenderRequest(xr *unstructured.Unstructured, observed []*unstructured.Unstructured, in *unstructured.Unstructured) (*fnv1.RunFunctionRequest, error) {
+	if in == nil {
+		in = &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "protection.fn.crossplane.io/v1beta1",
+			"kind":       "Input",
+		}}
+	}
+	input, err := resource.AsStruct(in)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert function input")
+	}
+	oxr, err := resource.AsStruct(xr)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert composite resource")
+	}
+	dxr, err := resource.AsStruct(xr)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert composite resource")
+	}
+
+	req := &fnv1.RunFunctionRequest{
+		Input:    input,
+		Observed: &fnv1.State{Composite: &fnv1.Resource{Resource: oxr}, Resources: map[string]*fnv1.Resource{}},
+		Desired:  &fnv1.State{Composite: &fnv1.Resource{Resource: dxr}, Resources: map[string]*fnv1.Resource{}},
+	}
+	for _, o := range observed {
+		name := o.GetAnnotations()[AnnotationCompositionResourceName]
+		if name == "" {
+			return nil, errors.Errorf("%s %s has no %s annotation", o.GetKind(), o.GetName(), AnnotationCompositionResourceName)
+		}
+		if _, ok := req.GetObserved().GetResources()[name]; ok {
+			return nil, errors.Errorf("more than one observed resource has composition resource name %q", name)
+		}
+		ocd, err := resource.AsStruct(o)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot convert observed resource %q", name)
+		}
+		dcd, err := resource.AsStruct(o)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot convert observed resource %q", name)
+		}
+		req.Observed.Resources[name] = &fnv1.Resource{Resource: ocd}
+		req.Desired.Resources[name] = &fnv1.Resource{Resource: dcd}
+	}
+	return req, nil
+}
+
+// WriteRenderOutput writes the desired Usages and the results of a response
+// as a stream of YAML documents, sorted by resource name.
+func WriteRenderOutput(w io.Writer, rsp *fnv1.RunFunctionResponse) error {
+	desired := rsp.GetDesired().GetResources()
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	docs := []any{}
+	for _, name := range names {
+		u := &unstructured.Unstructured{}
+		if err := resource.AsObject(desired[name].GetResource(), u); err != nil {
+			return errors.Wrapf(err, "cannot convert desired resource %q", name)
+		}
+		if isUsage(u) {
+			docs = append(docs, u.Object)
+		}
+	}
+	for _, r := range rsp.GetResults() {
+		result := map[string]any{
+			"apiVersion": RenderResultAPIVersion,
+			"kind":       "Result",
+			"severity":   r.GetSeverity().String(),
+			"message":    r.GetMessage(),
+		}
+		if r.Reason != nil {
+			result["reason"] = r.GetReason()
+		}
+		docs = append(docs, result)
+	}
+
+	for _, d := range docs {
+		b, err := yaml.Marshal(d)
+		if err != nil {
+			return errors.Wrap(err, "cannot marshal output")
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", b); err != nil {
+			return errors.Wrap(err, "cannot write output")
+		}
+	}
+	return nil
+}
+
+// isUsage returns true if the object is a Usage or ClusterUsage.
+func isUsage(u *unstructured.Unstructured) bool {
+	switch u.GetAPIVersion() {
+	case ProtectionGroupVersion, ProtectionV1GroupVersion:
+		return strings.HasSuffix(u.GetKind(), "Usage")
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+func TestReadObjects(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"vpc.yaml": `
+apiVersion: ec2.aws.upbound.io/v1beta1
+kind: VPC
+metadata:
+  name: my-vpc
+---
+apiVersion: ec2.aws.upbound.io/v1beta1
+kind: Subnet
+metadata:
+  name: my-subnet
+`,
+		"bucket.json": `{"apiVersion": "s3.aws.upbound.io/v1beta1", "kind": "Bucket", "metadata": {"name": "my-bucket"}}`,
+		"README.md":   "Not a manifest",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	objs, err := ReadObjects(dir)
+	if err != nil {
+		t.Fatalf("ReadObjects(...): %v", err)
+	}
+	got := make([]string, len(objs))
+	for i, o := range objs {
+		got[i] = o.GetKind() + "/" + o.GetName()
+	}
+	want := []string{"Bucket/my-bucket", "VPC/my-vpc", "Subnet/my-subnet"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadObjects(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestReadRunFunctionRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "request.yaml")
+	req := `
+meta:
+  tag: hello
+observed:
+  composite:
+    resource:
+      apiVersion: test.crossplane.io/v1
+      kind: TestXR
+      metadata:
+        name: my-test-xr
+`
+	if err := os.WriteFile(path, []byte(req), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &fnv1.RunFunctionRequest{
+		Meta: &fnv1.RequestMeta{Tag: "hello"},
+		Observed: &fnv1.State{
+			Composite: &fnv1.Resource{
+				Resource: resource.MustStructJSON(`{
+					"apiVersion": "test.crossplane.io/v1",
+					"kind": "TestXR",
+					"metadata": {"name": "my-test-xr"}
+				}`),
+			},
+		},
+	}
+	got, err := ReadRunFunctionRequest(path)
+	if err != nil {
+		t.Fatalf("ReadRunFunctionRequest(...): %v", err)
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("ReadRunFunctionRequest(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestNewRenderRequest(t *testing.T) {
+	xr := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "test.crossplane.io/v1",
+		"kind":       "TestXR",
+		"metadata":   map[string]any{"name": "my-test-xr"},
+	}}
+	vpc := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "ec2.aws.upbound.io/v1beta1",
+		"kind":       "VPC",
+		"metadata": map[string]any{
+			"name":        "my-vpc",
+			"annotations": map[string]any{AnnotationCompositionResourceName: "vpc"},
+		},
+	}}
+	unnamed := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "ec2.aws.upbound.io/v1beta1",
+		"kind":       "Subnet",
+		"metadata":   map[string]any{"name": "my-subnet"},
+	}}
+
+	type args struct {
+		observed []*unstructured.Unstructured
+	}
+	type want struct {
+		req *fnv1.RunFunctionRequest
+		err bool
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"ObservedResources": {
+			reason: "Observed resources should be observed and desired by composition resource name",
+			args:   args{observed: []*unstructured.Unstructured{vpc}},
+			want: want{
+				req: &fnv1.RunFunctionRequest{
+					Input: resource.MustStructJSON(`{"apiVersion": "protection.fn.crossplane.io/v1beta1", "kind": "Input"}`),
+					Observed: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructObject(xr)},
+						Resources: map[string]*fnv1.Resource{"vpc": {Resource: resource.MustStructObject(vpc)}},
+					},
+					Desired: &fnv1.State{
+						Composite: &fnv1.Resource{Resource: resource.MustStructObject(xr)},
+						Resources: map[string]*fnv1.Resource{"vpc": {Resource: resource.MustStructObject(vpc)}},
+					},
+				},
+			},
+		},
+		"MissingCompositionResourceName": {
+			reason: "An observed resource without a composition resource name should return an error",
+			args:   args{observed: []*unstructured.Unstructured{unnamed}},
+			want:   want{err: true},
+		},
+		"DuplicateCompositionResourceName": {
+			reason: "Observed resources with the same composition resource name should return an error",
+			args:   args{observed: []*unstructured.Unstructured{vpc, vpc}},
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := NewRenderRequest(xr, tc.args.observed, nil)
+			if (err != nil) != tc.want.err {
+				t.Fatalf("%s\nNewRenderRequest(...): want error %t, got %v", tc.reason, tc.want.err, err)
+			}
+			if diff := cmp.Diff(tc.want.req, got, protocmp.Transform()); diff != "" {
+				t.Errorf("%s\nNewRenderRequest(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWriteRenderOutput(t *testing.T) {
+	rsp := &fnv1.RunFunctionResponse{
+		Desired: &fnv1.State{
+			Resources: map[string]*fnv1.Resource{
+				"vpc": {Resource: resource.MustStructJSON(`{
+					"apiVersion": "ec2.aws.upbound.io/v1beta1",
+					"kind": "VPC",
+					"metadata": {"name": "my-vpc"}
+				}`)},
+				"vpc-usage": {Resource: resource.MustStructJSON(`{
+					"apiVersion": "protection.crossplane.io/v1beta1",
+					"kind": "ClusterUsage",
+					"metadata": {"name": "vpc-my-vpc-2a782e-fn-protection"},
+					"spec": {"reason": "created by function-deletion-protection via label protection.fn.crossplane.io/block-deletion"}
+				}`)},
+			},
+		},
+		Results: []*fnv1.Result{{
+			Severity: fnv1.Severity_SEVERITY_NORMAL,
+			Message:  "Deletion of the composite and 1 composed resource is blocked by Usages",
+		}},
+	}
+	want := `---
+apiVersion: protection.crossplane.io/v1beta1
+kind: ClusterUsage
+metadata:
+  name: vpc-my-vpc-2a782e-fn-protection
+spec:
+  reason: created by function-deletion-protection via label protection.fn.crossplane.io/block-deletion
+---
+apiVersion: render.crossplane.io/v1beta1
+kind: Result
+message: Deletion of the composite and 1 composed resource is blocked by Usages
+severity: SEVERITY_NORMAL
+`
+
+	b := &bytes.Buffer{}
+	if err := WriteRenderOutput(b, rsp); err != nil {
+		t.Fatalf("WriteRenderOutput(...): %v", err)
+	}
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("WriteRenderOutput(...): -want, +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	protectionv1beta1 "github.com/crossplane/crossplane/v2/apis/protection/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+const (
+	// LabelOperationUsage is stamped on Usages created for required resources,
+	// so they can be found when detecting stale Usages.
+	LabelOperationUsage = "protection.fn.crossplane.io/operation-usage"
+	// RequirementsNameUsages requires the Usages created by Operations.
+	RequirementsNameUsages = "protection.fn.crossplane.io/usages"
+	// RequirementsNameClusterUsages requires the ClusterUsages created by
+	// Operations.
+	RequirementsNameClusterUsages = "protection.fn.crossplane.io/cluster-usages"
+
+	// StaleReasonTargetNotFound is reported when the resource a Usage refers
+	// to is not a required resource.
+	StaleReasonTargetNotFound = "target not found"
+	// StaleReasonTargetNotProtected is reported when the resource a Usage
+	// refers to no longer requires protection.
+	StaleReasonTargetNotProtected = "target is no longer protected"
+)
+
+// An ObjectReference identifies a Kubernetes object.
+type ObjectReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+}
+
+// A StaleUsage is a Usage previously created by an Operation that is no longer
+// required.
+type StaleUsage struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Name       string          `json:"name"`
+	Namespace  string          `json:"namespace,omitempty"`
+	Of         ObjectReference `json:"of"`
+	Reason     string          `json:"reason"`
+}
+
+// UsageRequirements requires the Usages and ClusterUsages created by
+// Operations in all namespaces.
+func UsageRequirements() map[string]*fnv1.ResourceSelector {
+	match := func() *fnv1.ResourceSelector_MatchLabels {
+		return &fnv1.ResourceSelector_MatchLabels{
+			MatchLabels: &fnv1.MatchLabels{Labels: map[string]string{LabelOperationUsage: "true"}},
+		}
+	}
+	return map[string]*fnv1.ResourceSelector{
+		RequirementsNameUsages: {
+			ApiVersion: ProtectionGroupVersion,
+			Kind:       protectionv1beta1.UsageKind,
+			Match:      match(),
+		},
+		RequirementsNameClusterUsages: {
+			ApiVersion: ProtectionGroupVersion,
+			Kind:       protectionv1beta1.ClusterUsageKind,
+			Match:      match(),
+		},
+	}
+}
+
+// TakeUsages removes the Usages and ClusterUsages requested by
+// UsageRequirements from the required resources and returns them. It returns
+// false if Crossplane has not yet supplied them.
+func TakeUsages(rr map[string][]resource.Required) ([]resource.Required, bool) {
+	usages, okUsages := rr[RequirementsNameUsages]
+	clusterUsages, okClusterUsages := rr[RequirementsNameClusterUsages]
+	delete(rr, RequirementsNameUsages)
+	delete(rr, RequirementsNameClusterUsages)
+	return append(usages, clusterUsages...), okUsages && okClusterUsages
+}
+
+// FindStaleUsages returns the existing Usages that are not in the desired
+// Usages. The required resources are used to determine whether the target of
+// a stale Usage still exists.
+func FindStaleUsages(existing []resource.Required, desired map[resource.Name]*resource.DesiredComposed, rr map[string][]resource.Required) []StaleUsage {
+	want := map[ObjectReference]bool{}
+	for _, d := range desired {
+		want[objectReference(&d.Resource.Unstructured)] = true
+	}
+
+	stale := []StaleUsage{}
+	for _, e := range existing {
+		if e.Resource == nil || want[objectReference(e.Resource)] {
+			continue
+		}
+		of := usageTarget(e.Resource)
+		reason := StaleReasonTargetNotFound
+		if requiredResourceExists(rr, of) {
+			reason = StaleReasonTargetNotProtected
+		}
+		stale = append(stale, StaleUsage{
+			APIVersion: e.Resource.GetAPIVersion(),
+			Kind:       e.Resource.GetKind(),
+			Name:       e.Resource.GetName(),
+			Namespace:  e.Resource.GetNamespace(),
+			Of:         of,
+			Reason:     reason,
+		})
+	}
+	return stale
+}
+
+// objectReference returns a reference to an object.
+func objectReference(u *unstructured.Unstructured) ObjectReference {
+	return ObjectReference{
+		APIVersion: u.GetAPIVersion(),
+		Kind:       u.GetKind(),
+		Name:       u.GetName(),
+		Namespace:  u.GetNamespace(),
+	}
+}
+
+// usageTarget returns the resource a Usage refers to. The target of a
+// namespaced Usage defaults to the Usage's namespace.
+func usageTarget(u *unstructured.Unstructured) ObjectReference {
+	t := ObjectReference{}
+	t.APIVersion, _, _ = unstructured.NestedString(u.Object, "spec", "of", "apiVersion")
+	t.Kind, _, _ = unstructured.NestedString(u.Object, "spec", "of", "kind")
+	t.Name, _, _ = unstructured.NestedString(u.Object, "spec", "of", "resourceRef", "name")
+	t.Namespace, _, _ = unstructured.NestedString(u.Object, "spec", "of", "resourceRef", "namespace")
+	if t.Namespace == "" {
+		t.Namespace = u.GetNamespace()
+	}
+	return t
+}
+
+// requiredResourceExists returns true if the target is a required resource.
+func requiredResourceExists(rr map[string][]resource.Required, t ObjectReference) bool {
+	for _, v := range rr {
+		for _, r := range v {
+			if r.Resource != nil && objectReference(r.Resource) == t {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+func TestFindStaleUsages(t *testing.T) {
+	namespace := func(name string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata": map[string]any{
+				"name": name,
+			},
+		}}
+	}
+	usage := func(kind, name, ns string, of ObjectReference) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": ProtectionGroupVersion,
+			"kind":       kind,
+			"metadata": map[string]any{
+				"name": name,
+			},
+			"spec": map[string]any{
+				"of": map[string]any{
+					"apiVersion":  of.APIVersion,
+					"kind":        of.Kind,
+					"resourceRef": map[string]any{"name": of.Name},
+				},
+			},
+		}}
+		if ns != "" {
+			u.SetNamespace(ns)
+		}
+		return u
+	}
+	desired := func(u *unstructured.Unstructured) *resource.DesiredComposed {
+		return &resource.DesiredComposed{Resource: &composed.Unstructured{Unstructured: *u}}
+	}
+
+	prod := ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "prod"}
+	dev := ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "dev"}
+	gone := ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "gone"}
+	cm := ObjectReference{APIVersion: "v1", Kind: "ConfigMap", Name: "config", Namespace: "prod"}
+
+	type args struct {
+		existing []resource.Required
+		desired  map[resource.Name]*resource.DesiredComposed
+		rr       map[string][]resource.Required
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   []StaleUsage
+	}{
+		"NoExistingUsages": {
+			reason: "There are no stale Usages without existing Usages",
+			args: args{
+				desired: map[resource.Name]*resource.DesiredComposed{
+					"prod": desired(usage("ClusterUsage", "prod-usage", "", prod)),
+				},
+			},
+			want: []StaleUsage{},
+		},
+		"DesiredUsage": {
+			reason: "An existing Usage that is still desired is not stale",
+			args: args{
+				existing: []resource.Required{{Resource: usage("ClusterUsage", "prod-usage", "", prod)}},
+				desired: map[resource.Name]*resource.DesiredComposed{
+					"prod": desired(usage("ClusterUsage", "prod-usage", "", prod)),
+				},
+				rr: map[string][]resource.Required{"namespaces": {{Resource: namespace("prod")}}},
+			},
+			want: []StaleUsage{},
+		},
+		"TargetNotProtected": {
+			reason: "A Usage of a required resource that is no longer protected is stale",
+			args: args{
+				existing: []resource.Required{{Resource: usage("ClusterUsage", "dev-usage", "", dev)}},
+				desired:  map[resource.Name]*resource.DesiredComposed{},
+				rr:       map[string][]resource.Required{"namespaces": {{Resource: namespace("dev")}}},
+			},
+			want: []StaleUsage{{
+				APIVersion: ProtectionGroupVersion,
+				Kind:       "ClusterUsage",
+				Name:       "dev-usage",
+				Of:         dev,
+				Reason:     StaleReasonTargetNotProtected,
+			}},
+		},
+		"TargetNotFound": {
+			reason: "A Usage of a resource that isn't required is stale",
+			args: args{
+				existing: []resource.Required{{Resource: usage("ClusterUsage", "gone-usage", "", gone)}},
+				desired:  map[resource.Name]*resource.DesiredComposed{},
+				rr:       map[string][]resource.Required{"namespaces": {{Resource: namespace("prod")}}},
+			},
+			want: []StaleUsage{{
+				APIVersion: ProtectionGroupVersion,
+				Kind:       "ClusterUsage",
+				Name:       "gone-usage",
+				Of:         gone,
+				Reason:     StaleReasonTargetNotFound,
+			}},
+		},
+		"NamespacedUsage": {
+			reason: "The target of a namespaced Usage should default to the Usage's namespace",
+			args: args{
+				existing: []resource.Required{{Resource: usage("Usage", "config-usage", "prod", cm)}},
+				desired:  map[resource.Name]*resource.DesiredComposed{},
+				rr: map[string][]resource.Required{"configmaps": {{Resource: &unstructured.Unstructured{Object: map[string]any{
+					"apiVersion": "v1",
+					"kind":       "ConfigMap",
+					"metadata":   map[string]any{"name": "config", "namespace": "prod"},
+				}}}}},
+			},
+			want: []StaleUsage{{
+				APIVersion: ProtectionGroupVersion,
+				Kind:       "Usage",
+				Name:       "config-usage",
+				Namespace:  "prod",
+				Of:         cm,
+				Reason:     StaleReasonTargetNotProtected,
+			}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := FindStaleUsages(tc.args.existing, tc.args.desired, tc.args.rr)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nFindStaleUsages(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestTakeUsages(t *testing.T) {
+	usage := resource.Required{Resource: &unstructured.Unstructured{Object: map[string]any{"kind": "Usage"}}}
+	clusterUsage := resource.Required{Resource: &unstructured.Unstructured{Object: map[string]any{"kind": "ClusterUsage"}}}
+	other := resource.Required{Resource: &unstructured.Unstructured{Object: map[string]any{"kind": "Namespace"}}}
+
+	type want struct {
+		usages  []resource.Required
+		fetched bool
+		rr      map[string][]resource.Required
+	}
+	cases := map[string]struct {
+		reason string
+		rr     map[string][]resource.Required
+		want   want
+	}{
+		"NotFetched": {
+			reason: "Usages have not been fetched until both requirements are present",
+			rr: map[string][]resource.Required{
+				RequirementsNameUsages: {usage},
+				"namespaces":           {other},
+			},
+			want: want{
+				usages: []resource.Required{usage},
+				rr:     map[string][]resource.Required{"namespaces": {other}},
+			},
+		},
+		"Fetched": {
+			reason: "Usages and ClusterUsages should be removed from the required resources",
+			rr: map[string][]resource.Required{
+				RequirementsNameUsages:        {usage},
+				RequirementsNameClusterUsages: {clusterUsage},
+				"namespaces":                  {other},
+			},
+			want: want{
+				usages:  []resource.Required{usage, clusterUsage},
+				fetched: true,
+				rr:      map[string][]resource.Required{"namespaces": {other}},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			usages, fetched := TakeUsages(tc.rr)
+			got := want{usages: usages, fetched: fetched, rr: tc.rr}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nTakeUsages(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/response"
+)
+
+const (
+	// ConditionTypeDeletionProtected is set on the composite to report whether
+	// it is protected.
+	ConditionTypeDeletionProtected = "DeletionProtected"
+	// ConditionReasonProtected is used when the composite is protected.
+	ConditionReasonProtected = "UsagesCreated"
+	// ConditionReasonNotProtected is used when nothing is protected.
+	ConditionReasonNotProtected = "NoUsagesCreated"
+
+	// UsageResourceSuffix is appended to the composition resource name of a
+	// protected composed resource to name its Usage.
+	UsageResourceSuffix = "-usage"
+)
+
+// A ProtectedResource is a resource protected by a Usage.
+type ProtectedResource struct {
+	// Name is the composition resource name of a composed resource.
+	Name   string `json:"name"`
+	Usage  string `json:"usage"`
+	Reason string `json:"reason"`
+}
+
+// A CompositeProtection is the Usage protecting the composite.
+type CompositeProtection struct {
+	Usage  string `json:"usage"`
+	Reason string `json:"reason"`
+}
+
+// A ProtectionStatus summarizes protection in the composite's status.
+type ProtectionStatus struct {
+	// Protected is true if the composite is protected.
+	Protected bool `json:"protected"`
+	// Count is the number of protected resources, including the composite.
+	Count     int                  `json:"count"`
+	Composite *CompositeProtection `json:"composite,omitempty"`
+	Resources []ProtectedResource  `json:"resources,omitempty"`
+}
+
+// NewProtectionStatus summarizes the Usages protecting the composite and its
+// composed resources. The composite Usage is empty if the composite isn't
+// protected.
+func NewProtectionStatus(compositeUsage map[resource.Name]*resource.DesiredComposed, composed []ProtectedResource) ProtectionStatus {
+	s := ProtectionStatus{Count: len(composed), Resources: composed}
+	for _, u := range compositeUsage {
+		s.Protected = true
+		s.Count++
+		s.Composite = &CompositeProtection{
+			Usage:  u.Resource.GetName(),
+			Reason: usageReason(&u.Resource.Unstructured),
+		}
+	}
+	return s
+}
+
+// ParseStatusFieldPath splits a dot separated field path such as
+// status.deletionProtection. The path must be within the composite's status.
+func ParseStatusFieldPath(path string) ([]string, error) {
+	fields := strings.Split(path, ".")
+	if len(fields) < 2 || fields[0] != "status" {
+		return nil, errors.Errorf("field path %q must be within status", path)
+	}
+	for _, f := range fields {
+		if f == "" || strings.ContainsAny(f, "[]") {
+			return nil, errors.Errorf("field path %q must be dot separated object fields", path)
+		}
+	}
+	return fields, nil
+}
+
+// SetProtectionStatus writes the protection status to the desired composite
+// at the supplied fields. It returns an error, without writing the status, if
+// the observed or desired composite has a value that isn't an object on the
+// path, for example because the XRD uses the field for something else.
+func SetProtectionStatus(oxr, dxr *resource.Composite, fields []string, s ProtectionStatus) error {
+	for _, xr := range []*resource.Composite{oxr, dxr} {
+		if err := objectFieldsOnPath(xr.Resource.Object, fields); err != nil {
+			return err
+		}
+	}
+	m := map[string]any{}
+	if err := convertViaJSON(&m, s); err != nil {
+		return errors.Wrap(err, "cannot convert protection status")
+	}
+	return errors.Wrap(unstructured.SetNestedMap(dxr.Resource.Object, m, fields...), "cannot set protection status")
+}
+
+// objectFieldsOnPath returns an error if any field that exists on the path is
+// not an object.
+func objectFieldsOnPath(obj map[string]any, fields []string) error {
+	cur := obj
+	for i, f := range fields {
+		v, ok := cur[f]
+		if !ok || v == nil {
+			return nil
+		}
+		m, ok := v.(map[string]any)
+		if !ok {
+			return errors.Errorf("%s is a %T, not an object", strings.Join(fields[:i+1], "."), v)
+		}
+		cur = m
+	}
+	return nil
+}
+
+// ProtectedResources returns the observed composed resources protected by the
+// supplied Usages, sorted by name.
+func ProtectedResources(usages map[resource.Name]*resource.DesiredComposed, observed map[resource.Name]resource.ObservedComposed) []ProtectedResource {
+	names := make(map[ObjectReference]resource.Name, len(observed))
+	for name, o := range observed {
+		names[objectReference(&o.Resource.Unstructured)] = name
+	}
+	protected := make([]ProtectedResource, 0, len(usages))
+	for _, u := range usages {
+		name, ok := names[usageTarget(&u.Resource.Unstructured)]
+		if !ok {
+			continue
+		}
+		protected = append(protected, ProtectedResource{
+			Name:   string(name),
+			Usage:  u.Resource.GetName(),
+			Reason: usageReason(&u.Resource.Unstructured),
+		})
+	}
+	slices.SortFunc(protected, func(a, b ProtectedResource) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return protected
+}
+
+// usageReason returns the reason of a Usage.
+func usageReason(u *unstructured.Unstructured) string {
+	reason, _, _ := unstructured.NestedString(u.Object, "spec", "reason")
+	return reason
+}
+
+// SetDeletionProtectedCondition sets the DeletionProtected condition on the
+// composite and claim, and a Normal result if the composite or any composed
+// resources are protected. The composite Usage is empty if the composite isn't
+// protected, for example because protection doesn't propagate up to it.
+func SetDeletionProtectedCondition(rsp *fnv1.RunFunctionResponse, compositeUsage map[resource.Name]*resource.DesiredComposed, composed []ProtectedResource) {
+	if len(compositeUsage) == 0 && len(composed) == 0 {
+		response.ConditionFalse(rsp, ConditionTypeDeletionProtected, ConditionReasonNotProtected).
+			WithMessage("No resources are protected by function-deletion-protection").
+			TargetCompositeAndClaim()
+		return
+	}
+
+	msg := "Composite isn't protected"
+	for _, u := range compositeUsage {
+		msg = "Composite is protected " + strings.TrimPrefix(usageReason(&u.Resource.Unstructured), ProtectionReason)
+	}
+	if len(composed) > 0 {
+		rs := make([]string, len(composed))
+		for i, p := range composed {
+			rs[i] = fmt.Sprintf("%s (%s)", p.Name, strings.TrimPrefix(p.Reason, ProtectionReason))
+		}
+		msg += ". Protected composed resources: " + strings.Join(rs, ", ")
+	}
+	response.ConditionTrue(rsp, ConditionTypeDeletionProtected, ConditionReasonProtected).
+		WithMessage(msg).
+		TargetCompositeAndClaim()
+	switch {
+	case len(compositeUsage) == 0 && len(composed) == 1:
+		msg = "Deletion of 1 composed resource is blocked by a Usage"
+	case len(compositeUsage) == 0:
+		msg = fmt.Sprintf("Deletion of %d composed resources is blocked by Usages", len(composed))
+	case len(composed) == 0:
+		msg = "Deletion of the composite is blocked by a Usage"
+	case len(composed) == 1:
+		msg = "Deletion of the composite and 1 composed resource is blocked by Usages"
+	default:
+		msg = fmt.Sprintf("Deletion of the composite and %d composed resources is blocked by Usages", len(composed))
+	}
+	response.Normal(rsp, msg).TargetComposite()
+}
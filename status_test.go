@@ -0,0 +1,263 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	"github.com/crossplane/function-sdk-go/resource/composite"
+)
+
+func usageForTest(name, reason string) *resource.DesiredComposed {
+	return &resource.DesiredComposed{Resource: &composed.Unstructured{Unstructured: unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": ProtectionGroupVersion,
+		"kind":       "ClusterUsage",
+		"metadata":   map[string]any{"name": name},
+		"spec":       map[string]any{"reason": reason},
+	}}}}
+}
+
+func TestProtectedResources(t *testing.T) {
+	of := func(u *resource.DesiredComposed, kind, name string) *resource.DesiredComposed {
+		_ = unstructured.SetNestedMap(u.Resource.Object, map[string]any{
+			"apiVersion": "example.org/v1",
+			"kind":       kind,
+			"resourceRef": map[string]any{
+				"name": name,
+			},
+		}, "spec", "of")
+		return u
+	}
+	observed := func(kind, name string) resource.ObservedComposed {
+		cd := composed.New()
+		cd.SetAPIVersion("example.org/v1")
+		cd.SetKind(kind)
+		cd.SetName(name)
+		return resource.ObservedComposed{Resource: cd}
+	}
+	usages := map[resource.Name]*resource.DesiredComposed{
+		"vpc-usage":           of(usageForTest("vpc-my-vpc-2a782e-fn-protection", ProtectionReasonLabel), "VPC", "my-vpc"),
+		"templated-key-usage": of(usageForTest("bucket-my-bucket-264df3-fn-protection", ProtectionReasonRule+" buckets"), "Bucket", "my-bucket"),
+		"unknown-usage":       of(usageForTest("subnet-my-subnet-5d1a2c-fn-protection", ProtectionReasonLabel), "Subnet", "my-subnet"),
+	}
+	oc := map[resource.Name]resource.ObservedComposed{
+		"vpc":    observed("VPC", "my-vpc"),
+		"bucket": observed("Bucket", "my-bucket"),
+	}
+	want := []ProtectedResource{
+		{Name: "bucket", Usage: "bucket-my-bucket-264df3-fn-protection", Reason: ProtectionReasonRule + " buckets"},
+		{Name: "vpc", Usage: "vpc-my-vpc-2a782e-fn-protection", Reason: ProtectionReasonLabel},
+	}
+
+	got := ProtectedResources(usages, oc)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ProtectedResources(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestSetDeletionProtectedCondition(t *testing.T) {
+	type args struct {
+		compositeUsage map[resource.Name]*resource.DesiredComposed
+		composed       []ProtectedResource
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   *fnv1.RunFunctionResponse
+	}{
+		"NotProtected": {
+			reason: "The condition should be False when the composite isn't protected",
+			args:   args{},
+			want: &fnv1.RunFunctionResponse{
+				Conditions: []*fnv1.Condition{{
+					Type:    ConditionTypeDeletionProtected,
+					Status:  fnv1.Status_STATUS_CONDITION_FALSE,
+					Reason:  ConditionReasonNotProtected,
+					Message: proto.String("No resources are protected by function-deletion-protection"),
+					Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+				}},
+			},
+		},
+		"ComposedResourcesProtected": {
+			reason: "The condition should list the protected composed resources and why",
+			args: args{
+				compositeUsage: map[resource.Name]*resource.DesiredComposed{
+					"xr-my-xr-usage": usageForTest("xr-my-xr-1a2b3c-fn-protection", ProtectionReasonCompositeChildResource),
+				},
+				composed: []ProtectedResource{
+					{Name: "bucket", Reason: ProtectionReasonRule + " buckets"},
+					{Name: "vpc", Reason: ProtectionReasonLabel},
+				},
+			},
+			want: &fnv1.RunFunctionResponse{
+				Conditions: []*fnv1.Condition{{
+					Type:    ConditionTypeDeletionProtected,
+					Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+					Reason:  ConditionReasonProtected,
+					Message: proto.String("Composite is protected because a composed resource is protected. Protected composed resources: bucket (via rule buckets), vpc (via label protection.fn.crossplane.io/block-deletion)"),
+					Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+				}},
+				Results: []*fnv1.Result{{
+					Severity: fnv1.Severity_SEVERITY_NORMAL,
+					Message:  "Deletion of the composite and 2 composed resources is blocked by Usages",
+					Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+				}},
+			},
+		},
+		"OnlyComposedResourcesProtected": {
+			reason: "The condition should be True when composed resources are protected but the composite isn't",
+			args: args{
+				composed: []ProtectedResource{
+					{Name: "vpc", Reason: ProtectionReasonLabel},
+				},
+			},
+			want: &fnv1.RunFunctionResponse{
+				Conditions: []*fnv1.Condition{{
+					Type:    ConditionTypeDeletionProtected,
+					Status:  fnv1.Status_STATUS_CONDITION_TRUE,
+					Reason:  ConditionReasonProtected,
+					Message: proto.String("Composite isn't protected. Protected composed resources: vpc (via label protection.fn.crossplane.io/block-deletion)"),
+					Target:  fnv1.Target_TARGET_COMPOSITE_AND_CLAIM.Enum(),
+				}},
+				Results: []*fnv1.Result{{
+					Severity: fnv1.Severity_SEVERITY_NORMAL,
+					Message:  "Deletion of 1 composed resource is blocked by a Usage",
+					Target:   fnv1.Target_TARGET_COMPOSITE.Enum(),
+				}},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rsp := &fnv1.RunFunctionResponse{}
+			SetDeletionProtectedCondition(rsp, tc.args.compositeUsage, tc.args.composed)
+			if diff := cmp.Diff(tc.want, rsp, protocmp.Transform()); diff != "" {
+				t.Errorf("%s\nSetDeletionProtectedCondition(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestParseStatusFieldPath(t *testing.T) {
+	cases := map[string]struct {
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		"Valid":        {path: "status.deletionProtection", want: []string{"status", "deletionProtection"}},
+		"Nested":       {path: "status.platform.protection", want: []string{"status", "platform", "protection"}},
+		"NotInStatus":  {path: "spec.deletionProtection", wantErr: true},
+		"StatusOnly":   {path: "status", wantErr: true},
+		"EmptySegment": {path: "status..protection", wantErr: true},
+		"Index":        {path: "status.items[0]", wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseStatusFieldPath(tc.path)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseStatusFieldPath(%q): want error %t, got %v", tc.path, tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ParseStatusFieldPath(%q): -want, +got:\n%s", tc.path, diff)
+			}
+		})
+	}
+}
+
+func TestSetProtectionStatus(t *testing.T) {
+	xr := func(status map[string]any) *resource.Composite {
+		c := &resource.Composite{Resource: composite.New()}
+		c.Resource.Object = map[string]any{
+			"apiVersion": "example.org/v1",
+			"kind":       "XNetwork",
+			"metadata":   map[string]any{"name": "my-network"},
+		}
+		if status != nil {
+			c.Resource.Object["status"] = status
+		}
+		return c
+	}
+	s := ProtectionStatus{
+		Protected: true,
+		Count:     2,
+		Composite: &CompositeProtection{Usage: "xnetwork-my-network-1a2b3c-fn-protection", Reason: ProtectionReasonCompositeChildResource},
+		Resources: []ProtectedResource{{Name: "vpc", Usage: "vpc-my-vpc-2a782e-fn-protection", Reason: ProtectionReasonLabel}},
+	}
+
+	type args struct {
+		oxr *resource.Composite
+		dxr *resource.Composite
+	}
+	type want struct {
+		status map[string]any
+		err    bool
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"WriteStatus": {
+			reason: "The summary should be written to the desired composite",
+			args: args{
+				oxr: xr(map[string]any{"ready": true}),
+				dxr: xr(nil),
+			},
+			want: want{
+				status: map[string]any{
+					"deletionProtection": map[string]any{
+						"protected": true,
+						"count":     float64(2),
+						"composite": map[string]any{
+							"usage":  "xnetwork-my-network-1a2b3c-fn-protection",
+							"reason": ProtectionReasonCompositeChildResource,
+						},
+						"resources": []any{
+							map[string]any{"name": "vpc", "usage": "vpc-my-vpc-2a782e-fn-protection", "reason": ProtectionReasonLabel},
+						},
+					},
+				},
+			},
+		},
+		"ObservedConflict": {
+			reason: "The summary should not be written if the observed composite uses the field for something else",
+			args: args{
+				oxr: xr(map[string]any{"deletionProtection": "enabled"}),
+				dxr: xr(nil),
+			},
+			want: want{err: true},
+		},
+		"DesiredConflict": {
+			reason: "The summary should not be written if another function set the field to something else",
+			args: args{
+				oxr: xr(nil),
+				dxr: xr(map[string]any{"deletionProtection": []any{"enabled"}}),
+			},
+			want: want{
+				status: map[string]any{"deletionProtection": []any{"enabled"}},
+				err:    true,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := SetProtectionStatus(tc.args.oxr, tc.args.dxr, []string{"status", "deletionProtection"}, s)
+			if (err != nil) != tc.want.err {
+				t.Fatalf("%s\nSetProtectionStatus(...): want error %t, got %v", tc.reason, tc.want.err, err)
+			}
+			got, _, _ := unstructured.NestedMap(tc.args.dxr.Resource.Object, "status")
+			if diff := cmp.Diff(tc.want.status, got); diff != "" {
+				t.Errorf("%s\nSetProtectionStatus(...): -want status, +got status:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
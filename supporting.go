@@ -0,0 +1,170 @@
+package main
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+)
+
+const (
+	// DefaultProviderConfigVersion is the version of ProviderConfigs that
+	// aren't configured in the Input.
+	DefaultProviderConfigVersion = "v1beta1"
+	// DefaultEnvironmentConfigAPIVersion is the apiVersion of
+	// EnvironmentConfig references without one.
+	DefaultEnvironmentConfigAPIVersion = "apiextensions.crossplane.io/v1beta1"
+)
+
+// A supportingResource is a resource a managed resource depends on.
+type supportingResource struct {
+	u      *unstructured.Unstructured
+	reason string
+	key    string
+}
+
+// ProviderConfigOf returns the ProviderConfig referenced by a managed
+// resource's spec.providerConfigRef, or nil if there is no reference. The
+// apiVersion is read from the supplied versions, keyed by the API group of the
+// managed resource. Otherwise it is the group of the managed resource, without
+// the service for Upbound family providers, for example aws.upbound.io for
+// s3.aws.upbound.io, and DefaultProviderConfigVersion.
+func ProviderConfigOf(mr *unstructured.Unstructured, versions map[string]string) *unstructured.Unstructured {
+	if mr == nil || mr.Object == nil {
+		return nil
+	}
+	ref, found, err := unstructured.NestedStringMap(mr.Object, "spec", "providerConfigRef")
+	if err != nil || !found || ref["name"] == "" {
+		return nil
+	}
+	group, _, _ := strings.Cut(mr.GetAPIVersion(), "/")
+	apiVersion, ok := versions[group]
+	if !ok {
+		if strings.HasSuffix(group, ".upbound.io") && strings.Count(group, ".") >= 3 {
+			_, group, _ = strings.Cut(group, ".")
+		}
+		apiVersion = group + "/" + DefaultProviderConfigVersion
+	}
+	pc := &unstructured.Unstructured{}
+	pc.SetAPIVersion(apiVersion)
+	pc.SetKind("ProviderConfig")
+	if k := ref["kind"]; k != "" {
+		pc.SetKind(k)
+	}
+	pc.SetName(ref["name"])
+	// Namespaced managed resources use a namespaced ProviderConfig, or a
+	// ClusterProviderConfig.
+	if pc.GetKind() == "ProviderConfig" {
+		pc.SetNamespace(mr.GetNamespace())
+	}
+	return pc
+}
+
+// ConnectionSecretOf returns the Secret referenced by a resource's
+// spec.writeConnectionSecretToRef, or nil if there is no reference. Secrets of
+// namespaced resources are in the resource's namespace.
+func ConnectionSecretOf(u *unstructured.Unstructured) *unstructured.Unstructured {
+	if u == nil || u.Object == nil {
+		return nil
+	}
+	ref, found, err := unstructured.NestedStringMap(u.Object, "spec", "writeConnectionSecretToRef")
+	if err != nil || !found || ref["name"] == "" {
+		return nil
+	}
+	namespace := ref["namespace"]
+	if u.GetNamespace() != "" {
+		namespace = u.GetNamespace()
+	}
+	if namespace == "" {
+		return nil
+	}
+	s := &unstructured.Unstructured{}
+	s.SetAPIVersion("v1")
+	s.SetKind("Secret")
+	s.SetName(ref["name"])
+	s.SetNamespace(namespace)
+	return s
+}
+
+// EnvironmentConfigsOf returns the EnvironmentConfigs referenced by a
+// composite's spec.environmentConfigRefs.
+func EnvironmentConfigsOf(xr *unstructured.Unstructured) []*unstructured.Unstructured {
+	ecs := []*unstructured.Unstructured{}
+	if xr == nil || xr.Object == nil {
+		return ecs
+	}
+	refs, _, _ := unstructured.NestedSlice(xr.Object, "spec", "environmentConfigRefs")
+	for _, r := range refs {
+		ref, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := ref["name"].(string)
+		if name == "" {
+			continue
+		}
+		apiVersion, _ := ref["apiVersion"].(string)
+		if apiVersion == "" {
+			apiVersion = DefaultEnvironmentConfigAPIVersion
+		}
+		ec := &unstructured.Unstructured{}
+		ec.SetAPIVersion(apiVersion)
+		ec.SetKind("EnvironmentConfig")
+		ec.SetName(name)
+		ecs = append(ecs, ec)
+	}
+	return ecs
+}
+
+// ProtectSupportingResources creates Usages that block deletion of the
+// ProviderConfig, connection Secret and the composite's EnvironmentConfigs
+// until each protected composed resource has been deleted.
+func (f *Function) ProtectSupportingResources(protected []ProtectedResource, observedComposed map[resource.Name]resource.ObservedComposed, xr *unstructured.Unstructured, versions map[string]string, policy *Policy, g *UsageGenerator) (map[resource.Name]*resource.DesiredComposed, error) {
+	ecs := EnvironmentConfigsOf(xr)
+	dc := map[resource.Name]*resource.DesiredComposed{}
+	for _, p := range protected {
+		name := resource.Name(p.Name)
+		observed, ok := observedComposed[name]
+		if !ok {
+			continue
+		}
+		by := &observed.Resource.Unstructured
+
+		supporting := []supportingResource{}
+		if pc := ProviderConfigOf(by, versions); pc != nil {
+			supporting = append(supporting, supportingResource{u: pc, reason: ProtectionReasonProviderConfig, key: p.Name + "-providerconfig"})
+		}
+		if s := ConnectionSecretOf(by); s != nil {
+			supporting = append(supporting, supportingResource{u: s, reason: ProtectionReasonConnectionSecret, key: p.Name + "-connection-secret"})
+		}
+		for _, ec := range ecs {
+			supporting = append(supporting, supportingResource{u: ec, reason: ProtectionReasonEnvironmentConfig, key: p.Name + "-environmentconfig-" + ec.GetName()})
+		}
+
+		for _, s := range supporting {
+			f.log.Debug("protecting supporting resource", "kind", s.u.GetKind(), "name", s.u.GetName(), "by", p.Name)
+			usage, ok, err := g.Generate(s.u, by, s.reason, policy.ReplayDeletion(name, by))
+			if err != nil {
+				return dc, err
+			}
+			if !ok {
+				continue
+			}
+			usageComposed := composed.New()
+			if err := convertViaJSON(usageComposed, usage); err != nil {
+				return dc, errors.Wrap(err, "cannot convert usage to unstructured")
+			}
+			key, err := g.Key(resource.Name(strings.ToLower(s.key+UsageResourceSuffix)), s.u, by)
+			if err != nil {
+				return dc, err
+			}
+			if err := addUsage(dc, key, usageComposed); err != nil {
+				return dc, err
+			}
+		}
+	}
+	return dc, nil
+}
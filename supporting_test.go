@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+func TestProviderConfigOf(t *testing.T) {
+	mr := func(apiVersion, namespace string, ref map[string]any) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": apiVersion,
+			"kind":       "Bucket",
+			"metadata":   map[string]any{"name": "my-bucket", "namespace": namespace},
+			"spec":       map[string]any{},
+		}}
+		if ref != nil {
+			u.Object["spec"] = map[string]any{"providerConfigRef": ref}
+		}
+		return u
+	}
+	pc := func(apiVersion, kind, namespace string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion(apiVersion)
+		u.SetKind(kind)
+		u.SetName("default")
+		u.SetNamespace(namespace)
+		return u
+	}
+
+	cases := map[string]struct {
+		reason   string
+		mr       *unstructured.Unstructured
+		versions map[string]string
+		want     *unstructured.Unstructured
+	}{
+		"NoReference": {
+			reason: "A resource without a providerConfigRef has no ProviderConfig",
+			mr:     mr("s3.aws.upbound.io/v1beta2", "", nil),
+		},
+		"FamilyProvider": {
+			reason: "The ProviderConfig of an Upbound family provider should be served by the family's group",
+			mr:     mr("s3.aws.upbound.io/v1beta2", "", map[string]any{"name": "default"}),
+			want:   pc("aws.upbound.io/v1beta1", "ProviderConfig", ""),
+		},
+		"Namespaced": {
+			reason: "The ProviderConfig of a namespaced resource should be in its namespace",
+			mr:     mr("s3.aws.m.upbound.io/v1beta1", "my-namespace", map[string]any{"name": "default", "kind": "ProviderConfig"}),
+			want:   pc("aws.m.upbound.io/v1beta1", "ProviderConfig", "my-namespace"),
+		},
+		"ClusterProviderConfig": {
+			reason: "A ClusterProviderConfig should be cluster scoped",
+			mr:     mr("s3.aws.m.upbound.io/v1beta1", "my-namespace", map[string]any{"name": "default", "kind": "ClusterProviderConfig"}),
+			want:   pc("aws.m.upbound.io/v1beta1", "ClusterProviderConfig", ""),
+		},
+		"Version": {
+			reason:   "The apiVersion from the input should take precedence",
+			mr:       mr("kubernetes.crossplane.io/v1alpha2", "", map[string]any{"name": "default"}),
+			versions: map[string]string{"kubernetes.crossplane.io": "kubernetes.crossplane.io/v1alpha1"},
+			want:     pc("kubernetes.crossplane.io/v1alpha1", "ProviderConfig", ""),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ProviderConfigOf(tc.mr, tc.versions)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nProviderConfigOf(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnectionSecretOf(t *testing.T) {
+	secret := func(namespace string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion("v1")
+		u.SetKind("Secret")
+		u.SetName("my-secret")
+		u.SetNamespace(namespace)
+		return u
+	}
+
+	cases := map[string]struct {
+		reason    string
+		namespace string
+		ref       map[string]any
+		want      *unstructured.Unstructured
+	}{
+		"NoReference": {
+			reason: "A resource without a writeConnectionSecretToRef has no connection secret",
+		},
+		"ClusterScoped": {
+			reason: "The secret of a cluster scoped resource should be in the referenced namespace",
+			ref:    map[string]any{"name": "my-secret", "namespace": "crossplane-system"},
+			want:   secret("crossplane-system"),
+		},
+		"Namespaced": {
+			reason:    "The secret of a namespaced resource should be in its namespace",
+			namespace: "my-namespace",
+			ref:       map[string]any{"name": "my-secret"},
+			want:      secret("my-namespace"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			u := &unstructured.Unstructured{Object: map[string]any{
+				"metadata": map[string]any{"name": "my-db", "namespace": tc.namespace},
+				"spec":     map[string]any{},
+			}}
+			if tc.ref != nil {
+				u.Object["spec"] = map[string]any{"writeConnectionSecretToRef": tc.ref}
+			}
+			got := ConnectionSecretOf(u)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nConnectionSecretOf(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRunFunctionSupportingResources(t *testing.T) {
+	xr := resource.MustStructJSON(`{
+		"apiVersion": "example.org/v1",
+		"kind": "XDatabase",
+		"metadata": {"name": "my-db"},
+		"spec": {
+			"environmentConfigRefs": [
+				{"apiVersion": "apiextensions.crossplane.io/v1beta1", "kind": "EnvironmentConfig", "name": "prod"}
+			]
+		}
+	}`)
+	req := func(input string) *fnv1.RunFunctionRequest {
+		resources := map[string]*fnv1.Resource{
+			"instance": {Resource: resource.MustStructJSON(`{
+				"apiVersion": "rds.aws.m.upbound.io/v1beta1",
+				"kind": "Instance",
+				"metadata": {
+					"name": "my-instance",
+					"namespace": "my-namespace",
+					"labels": {"protection.fn.crossplane.io/block-deletion": "true"}
+				},
+				"spec": {
+					"providerConfigRef": {"name": "default", "kind": "ClusterProviderConfig"},
+					"writeConnectionSecretToRef": {"name": "my-instance-conn"}
+				}
+			}`)},
+			"cluster-instance": {Resource: resource.MustStructJSON(`{
+				"apiVersion": "rds.aws.upbound.io/v1beta1",
+				"kind": "Instance",
+				"metadata": {
+					"name": "my-cluster-instance",
+					"labels": {"protection.fn.crossplane.io/block-deletion": "true"}
+				},
+				"spec": {
+					"providerConfigRef": {"name": "default"},
+					"writeConnectionSecretToRef": {"name": "my-cluster-instance-conn", "namespace": "crossplane-system"}
+				}
+			}`)},
+			"subnet-group": {Resource: resource.MustStructJSON(`{
+				"apiVersion": "rds.aws.m.upbound.io/v1beta1",
+				"kind": "SubnetGroup",
+				"metadata": {"name": "my-subnet-group", "namespace": "my-namespace"},
+				"spec": {"providerConfigRef": {"name": "default", "kind": "ClusterProviderConfig"}}
+			}`)},
+		}
+		return &fnv1.RunFunctionRequest{
+			Input: resource.MustStructJSON(`{
+				"apiVersion": "protection.fn.crossplane.io/v1beta1",
+				"kind": "Input"` + input + `
+			}`),
+			Observed: &fnv1.State{Composite: &fnv1.Resource{Resource: xr}, Resources: resources},
+			Desired:  &fnv1.State{Composite: &fnv1.Resource{Resource: xr}, Resources: resources},
+		}
+	}
+
+	cases := map[string]struct {
+		reason string
+		input  string
+		want   map[string]string
+	}{
+		"Disabled": {
+			reason: "Supporting resources shouldn't be protected by default",
+			want: map[string]string{
+				"instance-usage":         ProtectionReasonLabel,
+				"cluster-instance-usage": ProtectionReasonLabel,
+				"xr-my-db-usage":         ProtectionReasonCompositeChildResource,
+			},
+		},
+		"Enabled": {
+			reason: "The ProviderConfig, connection secret and EnvironmentConfigs of protected resources should be protected, including the secret of a cluster scoped resource",
+			input:  `, "protectSupportingResources": true`,
+			want: map[string]string{
+				"instance-usage":                                ProtectionReasonLabel,
+				"instance-providerconfig-usage":                 ProtectionReasonProviderConfig,
+				"instance-connection-secret-usage":              ProtectionReasonConnectionSecret,
+				"instance-environmentconfig-prod-usage":         ProtectionReasonEnvironmentConfig,
+				"cluster-instance-usage":                        ProtectionReasonLabel,
+				"cluster-instance-providerconfig-usage":         ProtectionReasonProviderConfig,
+				"cluster-instance-connection-secret-usage":      ProtectionReasonConnectionSecret,
+				"cluster-instance-environmentconfig-prod-usage": ProtectionReasonEnvironmentConfig,
+				"xr-my-db-usage":                                ProtectionReasonCompositeChildResource,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := &Function{log: logging.NewNopLogger()}
+			rsp, err := f.RunFunction(context.Background(), req(tc.input))
+			if err != nil {
+				t.Fatalf("%s\nf.RunFunction(...): %v", tc.reason, err)
+			}
+			got := map[string]string{}
+			for name, r := range rsp.GetDesired().GetResources() {
+				if reason, ok, _ := unstructured.NestedString(r.GetResource().AsMap(), "spec", "reason"); ok {
+					got[name] = reason
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
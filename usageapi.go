@@ -0,0 +1,201 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"text/template"
+	"time"
+
+	v1beta1 "github.com/stevendborrelli/function-deletion-protection/input/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/crossplane/function-sdk-go/errors"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// SelectUsageAPI returns the API of the Usages generated for a request, and
+// why it was selected. Auto mode selects v2 Usages if the request uses a
+// Crossplane v2 feature. Otherwise it selects v1 Usages, which are served by
+// both Crossplane v1 and v2.
+func SelectUsageAPI(in *v1beta1.Input, req *fnv1.RunFunctionRequest) (v1beta1.UsageAPI, string) {
+	switch in.UsageAPI {
+	case v1beta1.UsageAPIV1, v1beta1.UsageAPIV2:
+		return in.UsageAPI, "set by usageAPI"
+	case "":
+		if in.EnableV1Mode {
+			return v1beta1.UsageAPIV1, "set by enableV1Mode"
+		}
+		return v1beta1.UsageAPIV2, "default"
+	}
+
+	if req.GetObserved().GetComposite() == nil {
+		return v1beta1.UsageAPIV2, "Operations require Crossplane v2"
+	}
+	xr := &unstructured.Unstructured{}
+	if err := resource.AsObject(req.GetObserved().GetComposite().GetResource(), xr); err == nil {
+		if xr.GetNamespace() != "" {
+			return v1beta1.UsageAPIV2, "the composite is namespaced"
+		}
+		if _, ok, _ := unstructured.NestedFieldNoCopy(xr.Object, "spec", "crossplane"); ok {
+			return v1beta1.UsageAPIV2, "the composite has spec.crossplane"
+		}
+	}
+	for _, r := range req.GetObserved().GetResources() {
+		cd := &unstructured.Unstructured{}
+		if err := resource.AsObject(r.GetResource(), cd); err == nil && cd.GetNamespace() != "" {
+			return v1beta1.UsageAPIV2, "a composed resource is namespaced"
+		}
+	}
+	return v1beta1.UsageAPIV1, "no Crossplane v2 features are used"
+}
+
+// A UsageGenerator generates the Usages for a request with the selected API.
+// It records the resources that the API cannot protect.
+type UsageGenerator struct {
+	// V1 generates Crossplane v1 Usages.
+	V1 bool
+
+	// Auto is true if the API was selected automatically.
+	Auto bool
+
+	// NameTemplate and KeyTemplate optionally template the names of Usages
+	// and their keys in the desired composed resources.
+	NameTemplate *template.Template
+	KeyTemplate  *template.Template
+
+	// ReasonTemplate optionally templates the reasons of Usages.
+	ReasonTemplate *template.Template
+
+	// Values are passed to the templates. The resource fields are set for
+	// each Usage.
+	Values UsageTemplateValues
+
+	// Labels and Annotations are added to every Usage. The standard labels
+	// take precedence.
+	Labels      map[string]string
+	Annotations map[string]string
+
+	// Unsupported are the namespaced resources that v1 Usages cannot refer
+	// to.
+	Unsupported []ObjectReference
+
+	// Now is the time deletion overrides are evaluated at. Defaults to the
+	// current time.
+	Now time.Time
+
+	// Overrides are the Usages that weren't generated because their resource
+	// has the allow-deletion annotation.
+	Overrides []DeletionOverride
+}
+
+// NewUsageGenerator returns a UsageGenerator for the supplied API, using the
+// name and key templates of the input.
+func NewUsageGenerator(in *v1beta1.Input, api v1beta1.UsageAPI, composite string) (*UsageGenerator, error) {
+	nt, err := ParseUsageTemplate("usageNameTemplate", in.UsageNameTemplate, UsageTemplateValues{})
+	if err != nil {
+		return nil, err
+	}
+	kt, err := ParseUsageTemplate("usageKeyTemplate", in.UsageKeyTemplate, UsageTemplateValues{})
+	if err != nil {
+		return nil, err
+	}
+	rt, err := ParseUsageTemplate("usageReasonTemplate", in.UsageReasonTemplate, UsageReasonValues{})
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateUsageMetadata(in.UsageLabels, in.UsageAnnotations); err != nil {
+		return nil, err
+	}
+	return &UsageGenerator{
+		V1:             api == v1beta1.UsageAPIV1,
+		Auto:           in.UsageAPI == v1beta1.UsageAPIAuto,
+		NameTemplate:   nt,
+		KeyTemplate:    kt,
+		ReasonTemplate: rt,
+		Values:         UsageTemplateValues{Composite: composite, Step: in.Step},
+		Labels:         in.UsageLabels,
+		Annotations:    in.UsageAnnotations,
+	}, nil
+}
+
+// Generate returns a Usage of u, optionally by another resource. It returns
+// false if v1 Usages are generated and u or by is namespaced, or if u has the
+// allow-deletion annotation.
+func (g *UsageGenerator) Generate(u, by *unstructured.Unstructured, reason string, replayDeletion bool) (map[string]any, bool, error) {
+	if g.V1 {
+		for _, r := range []*unstructured.Unstructured{u, by} {
+			if r != nil && r.GetNamespace() != "" {
+				if ref := objectReference(r); !slices.Contains(g.Unsupported, ref) {
+					g.Unsupported = append(g.Unsupported, ref)
+				}
+				return nil, false, nil
+			}
+		}
+	}
+	usage := GenerateUsage(u, by, reason, replayDeletion, g.V1)
+	labels := maps.Clone(g.Labels)
+	if c := g.Values.Composite; c != "" && len(validation.IsValidLabelValue(c)) == 0 {
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[LabelComposite] = c
+	}
+	addUsageMetadata(usage, labels, g.Annotations)
+	if g.NameTemplate != nil {
+		name, err := templateName(g.NameTemplate, g.Values, u, by)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := unstructured.SetNestedField(usage, name, "metadata", "name"); err != nil {
+			return nil, false, errors.Wrap(err, "cannot set Usage name")
+		}
+	}
+	r, err := g.Reason(u, reason)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := unstructured.SetNestedField(usage, r, "spec", "reason"); err != nil {
+		return nil, false, errors.Wrap(err, "cannot set Usage reason")
+	}
+	now := g.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if ticket, until, ok := AllowDeletion(u, now); ok {
+		name, _, _ := unstructured.NestedString(usage, "metadata", "name")
+		g.Overrides = append(g.Overrides, DeletionOverride{Resource: objectReference(u), Ticket: ticket, Usage: name, Until: until})
+		return nil, false, nil
+	}
+	return usage, true, nil
+}
+
+// Key returns the key of the Usage of u, optionally by another resource, in
+// the desired composed resources. The supplied key is returned if there is no
+// key template.
+func (g *UsageGenerator) Key(key resource.Name, u, by *unstructured.Unstructured) (resource.Name, error) {
+	if g.KeyTemplate == nil {
+		return key, nil
+	}
+	name, err := templateName(g.KeyTemplate, g.Values, u, by)
+	return resource.Name(name), err
+}
+
+// Warnings returns a warning for each resource that could not be protected,
+// and each Usage dropped by a deletion override.
+func (g *UsageGenerator) Warnings() []error {
+	errs := make([]error, 0, len(g.Unsupported)+len(g.Overrides))
+	// Suggesting auto is no help if it selected v1 Usages.
+	fix := "set usageAPI to v2 or auto"
+	if g.Auto {
+		fix = "set usageAPI to v2"
+	}
+	for _, r := range g.Unsupported {
+		errs = append(errs, errors.Errorf("cannot protect namespaced %s %s/%s with a v1 Usage: %s", r.Kind, r.Namespace, r.Name, fix))
+	}
+	for _, o := range g.Overrides {
+		errs = append(errs, o.warning())
+	}
+	return errs
+}
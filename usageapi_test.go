@@ -0,0 +1,268 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1beta1 "github.com/stevendborrelli/function-deletion-protection/input/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+func TestSelectUsageAPI(t *testing.T) {
+	composition := func(xr string, composed ...string) *fnv1.RunFunctionRequest {
+		req := &fnv1.RunFunctionRequest{
+			Observed: &fnv1.State{
+				Composite: &fnv1.Resource{Resource: resource.MustStructJSON(xr)},
+				Resources: map[string]*fnv1.Resource{},
+			},
+		}
+		for _, cd := range composed {
+			req.Observed.Resources[cd] = &fnv1.Resource{Resource: resource.MustStructJSON(cd)}
+		}
+		return req
+	}
+	legacyXR := `{"apiVersion": "example.org/v1", "kind": "XNetwork", "metadata": {"name": "my-network"}, "spec": {"compositionRef": {"name": "network"}}}`
+
+	type args struct {
+		in  *v1beta1.Input
+		req *fnv1.RunFunctionRequest
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   v1beta1.UsageAPI
+	}{
+		"Default": {
+			reason: "v2 Usages should be generated by default",
+			args:   args{in: &v1beta1.Input{}, req: composition(legacyXR)},
+			want:   v1beta1.UsageAPIV2,
+		},
+		"EnableV1Mode": {
+			reason: "v1 Usages should be generated if enableV1Mode is set",
+			args:   args{in: &v1beta1.Input{EnableV1Mode: true}, req: composition(legacyXR)},
+			want:   v1beta1.UsageAPIV1,
+		},
+		"UsageAPIOverridesEnableV1Mode": {
+			reason: "usageAPI should take precedence over enableV1Mode",
+			args:   args{in: &v1beta1.Input{EnableV1Mode: true, UsageAPI: v1beta1.UsageAPIV2}, req: composition(legacyXR)},
+			want:   v1beta1.UsageAPIV2,
+		},
+		"AutoLegacyComposite": {
+			reason: "Auto mode should select v1 Usages if no v2 features are used",
+			args:   args{in: &v1beta1.Input{UsageAPI: v1beta1.UsageAPIAuto}, req: composition(legacyXR)},
+			want:   v1beta1.UsageAPIV1,
+		},
+		"AutoOperation": {
+			reason: "Auto mode should select v2 Usages for Operations",
+			args:   args{in: &v1beta1.Input{UsageAPI: v1beta1.UsageAPIAuto}, req: &fnv1.RunFunctionRequest{}},
+			want:   v1beta1.UsageAPIV2,
+		},
+		"AutoNamespacedComposite": {
+			reason: "Auto mode should select v2 Usages for a namespaced composite",
+			args: args{
+				in:  &v1beta1.Input{UsageAPI: v1beta1.UsageAPIAuto},
+				req: composition(`{"apiVersion": "example.org/v1", "kind": "Network", "metadata": {"name": "my-network", "namespace": "prod"}}`),
+			},
+			want: v1beta1.UsageAPIV2,
+		},
+		"AutoCrossplaneSpec": {
+			reason: "Auto mode should select v2 Usages for a composite with spec.crossplane",
+			args: args{
+				in:  &v1beta1.Input{UsageAPI: v1beta1.UsageAPIAuto},
+				req: composition(`{"apiVersion": "example.org/v1", "kind": "XNetwork", "metadata": {"name": "my-network"}, "spec": {"crossplane": {}}}`),
+			},
+			want: v1beta1.UsageAPIV2,
+		},
+		"AutoNamespacedComposedResource": {
+			reason: "Auto mode should select v2 Usages if a composed resource is namespaced",
+			args: args{
+				in:  &v1beta1.Input{UsageAPI: v1beta1.UsageAPIAuto},
+				req: composition(legacyXR, `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "config", "namespace": "prod"}}`),
+			},
+			want: v1beta1.UsageAPIV2,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, _ := SelectUsageAPI(tc.args.in, tc.args.req)
+			if got != tc.want {
+				t.Errorf("%s\nSelectUsageAPI(...): want %q, got %q", tc.reason, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestUsageGeneratorGenerate(t *testing.T) {
+	namespace := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]any{"name": "prod"},
+	}}
+	configMap := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]any{"name": "config", "namespace": "prod"},
+	}}
+
+	type args struct {
+		v1 bool
+		u  *unstructured.Unstructured
+		by *unstructured.Unstructured
+	}
+	type want struct {
+		ok          bool
+		unsupported []ObjectReference
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"V1ClusterScoped": {
+			reason: "v1 Usages should protect cluster scoped resources",
+			args:   args{v1: true, u: namespace},
+			want:   want{ok: true},
+		},
+		"V1Namespaced": {
+			reason: "v1 Usages cannot protect namespaced resources",
+			args:   args{v1: true, u: configMap},
+			want:   want{unsupported: []ObjectReference{objectReference(configMap)}},
+		},
+		"V1NamespacedBy": {
+			reason: "v1 Usages cannot be used by namespaced resources",
+			args:   args{v1: true, u: namespace, by: configMap},
+			want:   want{unsupported: []ObjectReference{objectReference(configMap)}},
+		},
+		"V2Namespaced": {
+			reason: "v2 Usages should protect namespaced resources",
+			args:   args{u: configMap},
+			want:   want{ok: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			g := &UsageGenerator{V1: tc.args.v1}
+			usage, ok, err := g.Generate(tc.args.u, tc.args.by, ProtectionReasonLabel, false)
+			if err != nil {
+				t.Fatalf("%s\ng.Generate(...): %v", tc.reason, err)
+			}
+			if ok != tc.want.ok || (usage != nil) != tc.want.ok {
+				t.Errorf("%s\ng.Generate(...): want ok %t, got %t", tc.reason, tc.want.ok, ok)
+			}
+			if diff := cmp.Diff(tc.want.unsupported, g.Unsupported); diff != "" {
+				t.Errorf("%s\ng.Generate(...): -want unsupported, +got unsupported:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUsageGeneratorWarnings(t *testing.T) {
+	configMap := ObjectReference{APIVersion: "v1", Kind: "ConfigMap", Name: "config", Namespace: "prod"}
+
+	cases := map[string]struct {
+		reason string
+		g      *UsageGenerator
+		want   []string
+	}{
+		"V1": {
+			reason: "A resource v1 Usages can't protect should suggest v2 or auto Usages",
+			g:      &UsageGenerator{V1: true, Unsupported: []ObjectReference{configMap}},
+			want:   []string{"cannot protect namespaced ConfigMap prod/config with a v1 Usage: set usageAPI to v2 or auto"},
+		},
+		"Auto": {
+			reason: "A resource v1 Usages can't protect shouldn't suggest auto if it selected v1 Usages",
+			g:      &UsageGenerator{V1: true, Auto: true, Unsupported: []ObjectReference{configMap}},
+			want:   []string{"cannot protect namespaced ConfigMap prod/config with a v1 Usage: set usageAPI to v2"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := []string{}
+			for _, err := range tc.g.Warnings() {
+				got = append(got, err.Error())
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\ng.Warnings(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUsageGeneratorTemplates(t *testing.T) {
+	bucket := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "s3.aws.upbound.io/v1beta1",
+		"kind":       "Bucket",
+		"metadata":   map[string]any{"name": "my-bucket"},
+	}}
+
+	type want struct {
+		name string
+		key  resource.Name
+		err  bool
+	}
+	cases := map[string]struct {
+		reason string
+		in     *v1beta1.Input
+		want   want
+	}{
+		"Default": {
+			reason: "Usages should have the default name and the supplied key if no templates are set",
+			in:     &v1beta1.Input{},
+			want:   want{name: "bucket-my-bucket-264df3-fn-protection", key: "bucket-usage"},
+		},
+		"Templates": {
+			reason: "Templated names and keys should be sanitized and hashed",
+			in: &v1beta1.Input{
+				Step:              "protect-prod",
+				UsageNameTemplate: "{{ .Step }}-{{ .Kind }}.{{ .Group }}-{{ .Name }}",
+				UsageKeyTemplate:  "{{ .Step }}-{{ .Composite }}-{{ .Name }}",
+			},
+			want: want{
+				name: "protect-prod-bucket-s3-aws-upbound-io-my-bucket-edc518",
+				key:  "protect-prod-my-xr-my-bucket-e61eaa",
+			},
+		},
+		"InvalidTemplate": {
+			reason: "A template that can't be parsed should return an error",
+			in:     &v1beta1.Input{UsageNameTemplate: "{{ .Name"},
+			want:   want{err: true},
+		},
+		"UnknownValue": {
+			reason: "A template that refers to an unknown value should return an error",
+			in:     &v1beta1.Input{UsageKeyTemplate: "{{ .Resource }}"},
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			g, err := NewUsageGenerator(tc.in, v1beta1.UsageAPIV2, "my-xr")
+			if (err != nil) != tc.want.err {
+				t.Fatalf("%s\nNewUsageGenerator(...): want error %t, got %v", tc.reason, tc.want.err, err)
+			}
+			if err != nil {
+				return
+			}
+			usage, _, err := g.Generate(bucket, nil, ProtectionReasonLabel, false)
+			if err != nil {
+				t.Fatalf("%s\ng.Generate(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.name, (&unstructured.Unstructured{Object: usage}).GetName()); diff != "" {
+				t.Errorf("%s\ng.Generate(...): -want name, +got name:\n%s", tc.reason, diff)
+			}
+			key, err := g.Key("bucket-usage", bucket, nil)
+			if err != nil {
+				t.Fatalf("%s\ng.Key(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.key, key); diff != "" {
+				t.Errorf("%s\ng.Key(...): -want key, +got key:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
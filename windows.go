@@ -0,0 +1,135 @@
+package main
+
+import (
+	"time"
+	_ "time/tzdata" // Time zones of protection windows must not depend on the image.
+
+	"github.com/robfig/cron/v3"
+	v1beta1 "github.com/stevendborrelli/function-deletion-protection/input/v1beta1"
+
+	"github.com/crossplane/function-sdk-go/errors"
+)
+
+// protectionWindow is a compiled v1beta1.ProtectionWindow.
+type protectionWindow struct {
+	name string
+
+	// schedule starts a window of the supplied duration.
+	schedule cron.Schedule
+	duration time.Duration
+
+	// start and end bound a single window.
+	start time.Time
+	end   time.Time
+}
+
+// ProtectionWindows are the windows of time during which resources are
+// protected. Resources are always protected if there are no windows.
+type ProtectionWindows []protectionWindow
+
+// A WindowState is the state of the protection windows at a point in time.
+type WindowState struct {
+	// Active is true if any window is active.
+	Active bool
+	// Window is the name of an active window.
+	Window string
+	// Next is the next time a window starts or ends. It is zero if no window
+	// starts or ends in the future.
+	Next time.Time
+}
+
+// NewProtectionWindows compiles the supplied windows. The returned error names
+// the first window that could not be compiled.
+func NewProtectionWindows(ws []v1beta1.ProtectionWindow) (ProtectionWindows, error) {
+	compiled := make(ProtectionWindows, 0, len(ws))
+	for i, w := range ws {
+		cw, err := compileWindow(w)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot compile protection window %d %q", i, w.Name)
+		}
+		compiled = append(compiled, cw)
+	}
+	return compiled, nil
+}
+
+func compileWindow(w v1beta1.ProtectionWindow) (protectionWindow, error) {
+	cw := protectionWindow{name: w.Name}
+	switch {
+	case w.Schedule != "" && (w.Start != "" || w.End != ""):
+		return cw, errors.New("a window must have either a schedule or a start and end, not both")
+	case w.Schedule != "":
+		spec := w.Schedule
+		if w.TimeZone != "" {
+			if _, err := time.LoadLocation(w.TimeZone); err != nil {
+				return cw, errors.Wrapf(err, "invalid time zone %q", w.TimeZone)
+			}
+			spec = "CRON_TZ=" + w.TimeZone + " " + spec
+		}
+		s, err := cron.ParseStandard(spec)
+		if err != nil {
+			return cw, errors.Wrapf(err, "invalid schedule %q", w.Schedule)
+		}
+		d, err := time.ParseDuration(w.Duration)
+		if err != nil {
+			return cw, errors.Wrapf(err, "invalid duration %q", w.Duration)
+		}
+		if d <= 0 {
+			return cw, errors.Errorf("duration %q must be positive", w.Duration)
+		}
+		cw.schedule, cw.duration = s, d
+	case w.Start != "" && w.End != "":
+		if w.TimeZone != "" || w.Duration != "" {
+			return cw, errors.New("timeZone and duration only apply to a schedule")
+		}
+		start, err := time.Parse(time.RFC3339, w.Start)
+		if err != nil {
+			return cw, errors.Wrapf(err, "invalid start %q", w.Start)
+		}
+		end, err := time.Parse(time.RFC3339, w.End)
+		if err != nil {
+			return cw, errors.Wrapf(err, "invalid end %q", w.End)
+		}
+		if !end.After(start) {
+			return cw, errors.Errorf("end %q must be after start %q", w.End, w.Start)
+		}
+		cw.start, cw.end = start, end
+	default:
+		return cw, errors.New("a window must have a schedule, or a start and end")
+	}
+	return cw, nil
+}
+
+// evaluate returns whether the window is active at the supplied time, and the
+// next time it starts or ends.
+func (w protectionWindow) evaluate(now time.Time) (bool, time.Time) {
+	if w.schedule != nil {
+		// The earliest start that would still be active now.
+		start := w.schedule.Next(now.Add(-w.duration))
+		if start.IsZero() || start.After(now) {
+			return false, start
+		}
+		return true, start.Add(w.duration)
+	}
+	switch {
+	case now.Before(w.start):
+		return false, w.start
+	case now.Before(w.end):
+		return true, w.end
+	}
+	return false, time.Time{}
+}
+
+// Evaluate returns the state of the windows at the supplied time.
+func (ws ProtectionWindows) Evaluate(now time.Time) WindowState {
+	s := WindowState{}
+	for _, w := range ws {
+		active, next := w.evaluate(now)
+		if active && !s.Active {
+			s.Active, s.Window = true, w.name
+		}
+		if !next.IsZero() && (s.Next.IsZero() || next.Before(s.Next)) {
+			s.Next = next
+		}
+	}
+	return s
+}
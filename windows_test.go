@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	v1beta1 "github.com/stevendborrelli/function-deletion-protection/input/v1beta1"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	fnv1 "github.com/crossplane/function-sdk-go/proto/v1"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+func TestNewProtectionWindows(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		w      v1beta1.ProtectionWindow
+		err    bool
+	}{
+		"Schedule": {
+			reason: "A schedule with a duration and time zone should compile",
+			w:      v1beta1.ProtectionWindow{Schedule: "0 9 * * 1-5", Duration: "8h", TimeZone: "Europe/London"},
+		},
+		"Range": {
+			reason: "A start and end should compile",
+			w:      v1beta1.ProtectionWindow{Start: "2026-12-15T00:00:00-05:00", End: "2027-01-05T00:00:00-05:00"},
+		},
+		"ScheduleAndRange": {
+			reason: "A window can't have both a schedule and a start and end",
+			w:      v1beta1.ProtectionWindow{Schedule: "0 9 * * *", Duration: "8h", Start: "2026-12-15T00:00:00Z", End: "2027-01-05T00:00:00Z"},
+			err:    true,
+		},
+		"InvalidSchedule": {
+			reason: "An invalid cron schedule should return an error",
+			w:      v1beta1.ProtectionWindow{Schedule: "every day", Duration: "8h"},
+			err:    true,
+		},
+		"MissingDuration": {
+			reason: "A schedule requires a duration",
+			w:      v1beta1.ProtectionWindow{Schedule: "0 9 * * *"},
+			err:    true,
+		},
+		"InvalidTimeZone": {
+			reason: "An unknown time zone should return an error",
+			w:      v1beta1.ProtectionWindow{Schedule: "0 9 * * *", Duration: "8h", TimeZone: "Mars/Olympus_Mons"},
+			err:    true,
+		},
+		"EndBeforeStart": {
+			reason: "A window must end after it starts",
+			w:      v1beta1.ProtectionWindow{Start: "2027-01-05T00:00:00Z", End: "2026-12-15T00:00:00Z"},
+			err:    true,
+		},
+		"Empty": {
+			reason: "A window must have a schedule or a start and end",
+			w:      v1beta1.ProtectionWindow{Name: "empty"},
+			err:    true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewProtectionWindows([]v1beta1.ProtectionWindow{tc.w})
+			if (err != nil) != tc.err {
+				t.Errorf("%s\nNewProtectionWindows(...): want error %t, got %v", tc.reason, tc.err, err)
+			}
+		})
+	}
+}
+
+func TestProtectionWindowsEvaluate(t *testing.T) {
+	businessHours := v1beta1.ProtectionWindow{Name: "business-hours", Schedule: "0 9 * * 1-5", Duration: "8h", TimeZone: "America/New_York"}
+	freeze := v1beta1.ProtectionWindow{Name: "freeze", Start: "2026-12-15T00:00:00Z", End: "2027-01-05T00:00:00Z"}
+	at := func(s string) time.Time {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t
+	}
+
+	cases := map[string]struct {
+		reason  string
+		windows []v1beta1.ProtectionWindow
+		now     time.Time
+		want    WindowState
+	}{
+		"ScheduleActive": {
+			reason:  "A scheduled window should be active after it starts in its time zone, until its duration elapses",
+			windows: []v1beta1.ProtectionWindow{businessHours},
+			now:     at("2026-10-14T15:00:00Z"), // Wednesday 11am in New York.
+			want:    WindowState{Active: true, Window: "business-hours", Next: at("2026-10-14T21:00:00Z")},
+		},
+		"ScheduleInactive": {
+			reason:  "A scheduled window should be inactive until it next starts",
+			windows: []v1beta1.ProtectionWindow{businessHours},
+			now:     at("2026-10-17T15:00:00Z"), // Saturday.
+			want:    WindowState{Next: at("2026-10-19T13:00:00Z")},
+		},
+		"RangeBefore": {
+			reason:  "A window should be inactive before it starts",
+			windows: []v1beta1.ProtectionWindow{freeze},
+			now:     at("2026-12-01T00:00:00Z"),
+			want:    WindowState{Next: at("2026-12-15T00:00:00Z")},
+		},
+		"RangeAfter": {
+			reason:  "A window should be inactive after it ends, and never start again",
+			windows: []v1beta1.ProtectionWindow{freeze},
+			now:     at("2027-02-01T00:00:00Z"),
+			want:    WindowState{},
+		},
+		"Multiple": {
+			reason:  "Resources should be protected while any window is active, until the next window starts or ends",
+			windows: []v1beta1.ProtectionWindow{businessHours, freeze},
+			now:     at("2026-12-15T15:00:00Z"),
+			want:    WindowState{Active: true, Window: "business-hours", Next: at("2026-12-15T22:00:00Z")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ws, err := NewProtectionWindows(tc.windows)
+			if err != nil {
+				t.Fatalf("NewProtectionWindows(...): %v", err)
+			}
+			got := ws.Evaluate(tc.now)
+			if diff := cmp.Diff(tc.want, got, cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) })); diff != "" {
+				t.Errorf("%s\nEvaluate(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRunFunctionProtectionWindows(t *testing.T) {
+	composed := func() map[string]*fnv1.Resource {
+		return map[string]*fnv1.Resource{
+			"subnet": {Resource: resource.MustStructJSON(`{
+				"apiVersion": "ec2.aws.upbound.io/v1beta1",
+				"kind": "Subnet",
+				"metadata": {"name": "my-subnet"},
+				"spec": {"forProvider": {"vpcIdRef": {"name": "my-vpc"}}}
+			}`)},
+			"rt": {Resource: resource.MustStructJSON(`{
+				"apiVersion": "ec2.aws.upbound.io/v1beta1",
+				"kind": "RouteTable",
+				"metadata": {"name": "my-rt"}
+			}`)},
+			"vpc": {Resource: resource.MustStructJSON(`{
+				"apiVersion": "ec2.aws.upbound.io/v1beta1",
+				"kind": "VPC",
+				"metadata": {"name": "my-vpc"}
+			}`)},
+		}
+	}
+	req := func(input string) *fnv1.RunFunctionRequest {
+		xr := resource.MustStructJSON(`{
+			"apiVersion": "test.crossplane.io/v1",
+			"kind": "TestXR",
+			"metadata": {
+				"name": "my-test-xr",
+				"labels": {
+					"protection.fn.crossplane.io/block-deletion": "true"
+				}
+			}
+		}`)
+		return &fnv1.RunFunctionRequest{
+			Input: resource.MustStructJSON(`{
+				"apiVersion": "protection.fn.crossplane.io/v1beta1",
+				"kind": "Input",
+				"cacheTTL": "1h",
+				"protectionWindows": [
+					{"name": "freeze", "start": "2026-12-15T00:00:00Z", "end": "2027-01-05T00:00:00Z"}
+				]
+				` + input + `
+			}`),
+			Observed: &fnv1.State{Composite: &fnv1.Resource{Resource: xr}, Resources: composed()},
+			Desired:  &fnv1.State{Composite: &fnv1.Resource{Resource: xr}, Resources: composed()},
+		}
+	}
+
+	type want struct {
+		usages  []string
+		ttl     time.Duration
+		results []string
+	}
+	cases := map[string]struct {
+		reason string
+		input  string
+		now    string
+		want   want
+	}{
+		"Inactive": {
+			reason: "No Usages should be generated outside of a window, and the response should expire when the window starts",
+			now:    "2026-12-14T23:30:00Z",
+			want: want{
+				usages:  []string{},
+				ttl:     30 * time.Minute,
+				results: []string{"Deletion protection is inactive outside of protection windows until 2026-12-15T00:00:00Z"},
+			},
+		},
+		"InactiveOrdered": {
+			reason: "Usages that order deletion should still be generated outside of a window",
+			input:  `, "inferDependencies": true, "deletionOrder": ["rt", "subnet"]`,
+			now:    "2026-12-14T23:30:00Z",
+			want: want{
+				usages:  []string{"rt-subnet-bb2d5a-dependency-usage", "subnet-vpc-a590d1-dependency-usage"},
+				ttl:     30 * time.Minute,
+				results: []string{"Deletion protection is inactive outside of protection windows until 2026-12-15T00:00:00Z"},
+			},
+		},
+		"Active": {
+			reason: "Usages should be generated during a window, and the response cached for the cacheTTL if the window ends later",
+			now:    "2026-12-20T00:00:00Z",
+			want: want{
+				usages:  []string{"xr-my-test-xr-usage"},
+				ttl:     time.Hour,
+				results: []string{"Deletion of the composite is blocked by a Usage"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			now, _ := time.Parse(time.RFC3339, tc.now)
+			f := &Function{log: logging.NewNopLogger(), now: func() time.Time { return now }}
+			rsp, err := f.RunFunction(context.Background(), req(tc.input))
+			if err != nil {
+				t.Fatalf("%s\nf.RunFunction(...): %v", tc.reason, err)
+			}
+			usages := []string{}
+			for name := range rsp.GetDesired().GetResources() {
+				if _, ok := composed()[name]; !ok {
+					usages = append(usages, name)
+				}
+			}
+			slices.Sort(usages)
+			if diff := cmp.Diff(tc.want.usages, usages); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want usages, +got usages:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.ttl, rsp.GetMeta().GetTtl().AsDuration()); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want ttl, +got ttl:\n%s", tc.reason, diff)
+			}
+			results := []string{}
+			for _, r := range rsp.GetResults() {
+				results = append(results, r.GetMessage())
+			}
+			if diff := cmp.Diff(tc.want.results, results); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want results, +got results:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}